@@ -0,0 +1,849 @@
+package platform
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/powerunit-io/platform/config"
+	"github.com/powerunit-io/platform/connections/adapters/file"
+	"github.com/powerunit-io/platform/connections/adapters/mqtt"
+	"github.com/powerunit-io/platform/connections/adapters/mqtt/mqtttest"
+	"github.com/powerunit-io/platform/connections/adapters/mqttsn"
+	"github.com/powerunit-io/platform/connections/adaptertest"
+	"github.com/powerunit-io/platform/events"
+	"github.com/powerunit-io/platform/logging"
+	"github.com/powerunit-io/platform/managers"
+	. "github.com/smartystreets/goconvey/convey"
+
+	MQTT "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
+)
+
+var (
+	TestMqttConnection = map[string]interface{}{
+		"network":  "tcp",
+		"address":  "localhost:1883",
+		"username": "",
+		"password": "",
+		"clientId": "adaptertest",
+		"topic":    "powerunit-io-bridge",
+	}
+
+	TestMqttsnConnection = map[string]interface{}{
+		"gatewayAddr": "localhost:1884",
+		"clientId":    "adaptertest",
+		"topic":       "powerunit-io-bridge",
+	}
+
+	TestMsgTrigger = `{"type": "t", "device_id": "bedroom-switch", "data": {"state": "on"}}`
+)
+
+// withConnection - Will return copy of mqtt test connection with key, value
+// pairs overridden (or removed when value is nil)
+func withConnection(overrides ...interface{}) map[string]interface{} {
+	connection := map[string]interface{}{}
+
+	for k, v := range TestMqttConnection {
+		connection[k] = v
+	}
+
+	for i := 0; i+1 < len(overrides); i += 2 {
+		if key := overrides[i].(string); overrides[i+1] == nil {
+			delete(connection, key)
+		} else {
+			connection[key] = overrides[i+1]
+		}
+	}
+
+	return map[string]interface{}{"connection": connection}
+}
+
+// testMqtt - Will build (not started) mqtt connection out of the config
+func testMqtt(name string, conf map[string]interface{}) *mqtt.Connection {
+	adapter, err := mqtt.NewAdapter(name, conf, logging.New(map[string]interface{}{}))
+	So(err, ShouldBeNil)
+
+	return adapter.(*mqtt.Connection)
+}
+
+// testMsg - Will build message received on the topic
+func testMsg(topic string, payload string) *TestMessage {
+	return &TestMessage{false, byte(0), false, topic, 01, []byte(payload)}
+}
+
+// testRecord - Will record messages into capture at path under the source
+func testRecord(path string, source string, topics ...string) {
+	recorder, err := file.NewRecorder(path, 0, 0)
+	So(err, ShouldBeNil)
+
+	recorder.SetSource(source)
+
+	for _, topic := range topics {
+		recorder.Record(topic, []byte(TestMsgTrigger), nil)
+	}
+
+	recorder.Close()
+}
+
+// testReplay - Will start replay of captures at path with connection key, value
+// pairs overridden
+func testReplay(name string, path interface{}, overrides ...interface{}) file.Adapter {
+	connection := map[string]interface{}{"path": path}
+
+	for i := 0; i+1 < len(overrides); i += 2 {
+		connection[overrides[i].(string)] = overrides[i+1]
+	}
+
+	replay, err := file.NewAdapter(name, map[string]interface{}{"connection": connection}, logging.New(map[string]interface{}{}))
+	So(err, ShouldBeNil)
+	So(replay.Start(nil), ShouldBeNil)
+
+	return replay
+}
+
+// TestMqttAdapterConformance - Run adapter conformance suite against mqtt adapter
+// using BrokerHandler as fake transport
+func TestMqttAdapterConformance(t *testing.T) {
+	logger := logging.New(map[string]interface{}{})
+
+	adaptertest.RunConformance(t, adaptertest.Suite{
+		New: func(name string, conf map[string]interface{}) (managers.Service, error) {
+			return mqtt.NewAdapter(name, conf, logger)
+		},
+		ValidConfig: map[string]interface{}{"connection": TestMqttConnection},
+		InvalidConfigs: map[string]map[string]interface{}{
+			"missing connection": {},
+			"invalid network":    withConnection("network", "udp"),
+			"invalid address":    withConnection("address", "localhost:99999"),
+			"invalid weight":     withConnection("address", []interface{}{"localhost", map[string]interface{}{"address": "other", "weight": 0}}),
+			"missing client id":  withConnection("clientId", nil),
+			"short client id":    withConnection("clientId", "a"),
+			"missing topic":      withConnection("topic", nil),
+		},
+		Emit: func(service managers.Service, topic string, payload []byte) {
+			msg := TestMessage{false, byte(0), false, topic, 01, payload}
+			service.(*mqtt.Connection).BrokerHandler(nil, &msg)
+		},
+		Payload: []byte(TestMsgTrigger),
+	})
+}
+
+// TestMqttsnAdapterConformance - Run adapter conformance suite against mqtt-sn
+// adapter using Emit as fake transport
+func TestMqttsnAdapterConformance(t *testing.T) {
+	logger := logging.New(map[string]interface{}{})
+
+	adaptertest.RunConformance(t, adaptertest.Suite{
+		New: func(name string, conf map[string]interface{}) (managers.Service, error) {
+			return mqttsn.NewAdapter(name, conf, logger)
+		},
+		ValidConfig: map[string]interface{}{"connection": TestMqttsnConnection},
+		InvalidConfigs: map[string]map[string]interface{}{
+			"missing connection": {},
+			"invalid gateway":    {"connection": map[string]interface{}{"gatewayAddr": "localhost", "clientId": "a", "topic": "t"}},
+			"long client id":     {"connection": map[string]interface{}{"gatewayAddr": "localhost:1884", "clientId": "abcdefghijklmnopqrstuvwxyz", "topic": "t"}},
+			"invalid qos":        {"connection": map[string]interface{}{"gatewayAddr": "localhost:1884", "clientId": "a", "topic": "t", "qos": 2}},
+		},
+		Emit: func(service managers.Service, topic string, payload []byte) {
+			msg := TestMessage{false, byte(0), false, topic, 01, payload}
+			service.(*mqttsn.Connection).Emit(&msg)
+		},
+		Payload: []byte(TestMsgTrigger),
+	})
+}
+
+// TestMqttsnEmitAfterStop - Ensure that Emit does not block on full events
+// channel once connection is stopped
+func TestMqttsnEmitAfterStop(t *testing.T) {
+
+	Convey("Emit Returns Once Connection Is Stopped", t, func() {
+		adapter, err := mqttsn.NewAdapter("test-mqttsn-emit", map[string]interface{}{"connection": TestMqttsnConnection}, logging.New(map[string]interface{}{}))
+		So(err, ShouldBeNil)
+
+		connection := adapter.(*mqttsn.Connection)
+		So(connection.Stop(), ShouldBeNil)
+
+		emitted := make(chan bool)
+
+		go func() {
+			for i := 0; i <= cap(connection.DrainEvents()); i++ {
+				connection.Emit(testMsg("devices/switch", TestMsgTrigger))
+			}
+			close(emitted)
+		}()
+
+		select {
+		case <-emitted:
+		case <-time.After(2 * time.Second):
+			t.Fatal("Emit blocked on stopped connection")
+		}
+	})
+}
+
+// TestFileAdapterConformance - Run adapter conformance suite against file replay
+// adapter using Start (replay of the capture) as fake transport
+func TestFileAdapterConformance(t *testing.T) {
+	logger := logging.New(map[string]interface{}{})
+	path := filepath.Join(t.TempDir(), "capture.ndjson")
+	capture := `{"topic": "devices/switch", "payload": ` + TestMsgTrigger + `, "timestamp": 1}` + "\n"
+
+	if err := ioutil.WriteFile(path, []byte(capture), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	suite := adaptertest.Suite{
+		New: func(name string, conf map[string]interface{}) (managers.Service, error) {
+			return file.NewAdapter(name, conf, logger)
+		},
+		ValidConfig: map[string]interface{}{"connection": map[string]interface{}{"path": path}},
+		InvalidConfigs: map[string]map[string]interface{}{
+			"missing connection": {},
+			"missing file":       {"connection": map[string]interface{}{"path": path + ".missing"}},
+			"invalid format":     {"connection": map[string]interface{}{"path": path, "format": "csv"}},
+		},
+		Emit: func(service managers.Service, topic string, payload []byte) {
+			service.(*file.Connection).Start(nil)
+		},
+		Payload: []byte(TestMsgTrigger),
+	}
+
+	adaptertest.RunConformance(t, suite)
+}
+
+// TestFileRecorderRoundTrip - Ensure that recorded capture replays the same
+// messages, including non json payloads
+func TestFileRecorderRoundTrip(t *testing.T) {
+
+	Convey("Recorded Messages Are Replayed", t, func() {
+		path := filepath.Join(t.TempDir(), "record.ndjson")
+
+		recorder, err := file.NewRecorder(path, 0, 0)
+		So(err, ShouldBeNil)
+
+		recorder.Record("devices/switch", []byte(TestMsgTrigger), map[string]interface{}{"qos": 1})
+		recorder.Record("devices/raw", []byte("not json"), nil)
+		recorder.Close()
+
+		event, err := testReplay("recorder-round-trip", path).WaitForMessage(time.Second)
+		So(err, ShouldBeNil)
+		So(event.Topic(), ShouldEqual, "devices/switch")
+		So(event.DeviceID, ShouldEqual, "bedroom-switch")
+	})
+}
+
+// TestFileReplayOrder - Ensure that captures of concurrent sources are replayed
+// in the order messages were recorded in
+func TestFileReplayOrder(t *testing.T) {
+
+	Convey("Captures Are Merged By Record Order", t, func() {
+		dir := t.TempDir()
+		first, second := filepath.Join(dir, "first.ndjson"), filepath.Join(dir, "second.ndjson")
+
+		testRecord(first, "source-0", "devices/a")
+		testRecord(second, "source-1", "devices/b")
+		testRecord(first, "source-0", "devices/c")
+
+		replay := testReplay("replay-order", []interface{}{first, second}, "order", file.SequenceOrder)
+		var previous uint64
+
+		for _, source := range []string{"source-0", "source-1", "source-0"} {
+			event, err := replay.WaitForMessage(time.Second)
+			So(err, ShouldBeNil)
+			So(event.Source, ShouldEqual, source)
+			So(event.Sequence, ShouldBeGreaterThan, previous)
+
+			previous = event.Sequence
+		}
+	})
+}
+
+// TestFileReplayOrderAcrossProcesses - Ensure that sequence order merges
+// captures of separate processes by timestamp and source before sequence
+func TestFileReplayOrderAcrossProcesses(t *testing.T) {
+
+	Convey("Captures Of Separate Processes Are Merged By Timestamp", t, func() {
+		dir := t.TempDir()
+		first, second := filepath.Join(dir, "first.ndjson"), filepath.Join(dir, "second.ndjson")
+
+		record := func(topic string, timestamp int, source string, seq int) string {
+			return fmt.Sprintf(`{"topic": %q, "payload": %s, "timestamp": %d, "source": %q, "seq": %d}`+"\n", topic, TestMsgTrigger, timestamp, source, seq)
+		}
+
+		So(ioutil.WriteFile(first, []byte(record("devices/b", 2, "a", 900)+record("devices/d", 3, "a", 901)), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(second, []byte(record("devices/a", 1, "b", 7)+record("devices/c", 2, "b", 8)), 0644), ShouldBeNil)
+
+		replay := testReplay("replay-order-processes", []interface{}{first, second}, "order", file.SequenceOrder)
+
+		for _, topic := range []string{"devices/a", "devices/b", "devices/c", "devices/d"} {
+			event, err := replay.WaitForMessage(time.Second)
+			So(err, ShouldBeNil)
+			So(event.Topic(), ShouldEqual, topic)
+		}
+	})
+}
+
+// TestMqttStartValidates - Ensure that Start refuses invalid configuration
+// instead of connecting with it
+func TestMqttStartValidates(t *testing.T) {
+
+	Convey("Invalid Configuration Is Not Started", t, func() {
+		So(testMqtt("start-validates", withConnection("network", "udp")).Start(nil), ShouldNotBeNil)
+	})
+}
+
+// TestMqttSubscriptionStats - Ensure that received messages are counted within
+// every subscription they match
+func TestMqttSubscriptionStats(t *testing.T) {
+
+	Convey("Messages Are Counted Per Subscription", t, func() {
+		connection := testMqtt("subscription-stats", withConnection("topic", "devices/switch"))
+		connection.AddSubscription("devices/#")
+		connection.BrokerHandler(nil, testMsg("devices/switch", TestMsgTrigger))
+
+		stats := connection.SubscriptionStats()
+		So(stats, ShouldHaveLength, 2)
+		So(stats[0].Messages, ShouldEqual, 1)
+		So(stats[1].Messages, ShouldEqual, 1)
+		So(stats[0].Granted, ShouldBeFalse)
+	})
+}
+
+// TestMqttTopicAllowList - Ensure that deny filters take precedence over allow
+// ones and that not allowed messages never become events
+func TestMqttTopicAllowList(t *testing.T) {
+
+	Convey("Only Allowed Topics Become Events", t, func() {
+		conf := withConnection("allowTopics", []interface{}{"tenant-a/#"}, "denyTopics", []interface{}{"tenant-a/secret/+"})
+		connection := testMqtt("topic-allow-list", conf)
+
+		for _, topic := range []string{"tenant-b/switch", "tenant-a/secret/switch", "tenant-a/switch"} {
+			connection.BrokerHandler(nil, testMsg(topic, TestMsgTrigger))
+		}
+
+		event, err := connection.WaitForMessage(100 * time.Millisecond)
+		So(err, ShouldBeNil)
+		So(event.Topic(), ShouldEqual, "tenant-a/switch")
+
+		_, err = connection.WaitForMessage(50 * time.Millisecond)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+// TestMqttPipeToKeepsOrder - Ensure that events buffered before PipeTo are
+// forwarded ahead of events received afterwards
+func TestMqttPipeToKeepsOrder(t *testing.T) {
+
+	Convey("Piped Events Keep Order", t, func() {
+		connection := testMqtt("pipe-to-order", withConnection("bufferSize", 4))
+
+		for _, topic := range []string{"switch/1", "switch/2"} {
+			connection.BrokerHandler(nil, testMsg(topic, TestMsgTrigger))
+		}
+
+		piped := make(chan events.Event, 4)
+		So(connection.PipeTo(piped), ShouldBeNil)
+
+		for _, topic := range []string{"switch/3", "switch/4"} {
+			connection.BrokerHandler(nil, testMsg(topic, TestMsgTrigger))
+		}
+
+		for _, topic := range []string{"switch/1", "switch/2", "switch/3", "switch/4"} {
+			select {
+			case event := <-piped:
+				So(event.Topic(), ShouldEqual, topic)
+			case <-time.After(time.Second):
+				So(fmt.Sprintf("event on (topic: %s) was not piped", topic), ShouldBeEmpty)
+			}
+		}
+
+		So(connection.Stop(), ShouldBeNil)
+	})
+}
+
+// TestMqttDelayOnStop - Ensure that events still delayed on Stop are flushed to
+// dead letters
+func TestMqttDelayOnStop(t *testing.T) {
+
+	Convey("Delayed Events Are Dead Lettered On Stop", t, func() {
+		connection := testMqtt("delay-on-stop", withConnection("delayOnStop", mqtt.FlushDelayed))
+
+		event, err := events.NewEvent(testMsg("switch", TestMsgTrigger))
+		So(err, ShouldBeNil)
+
+		So(connection.DelayEvent(event, time.Hour), ShouldBeNil)
+		So(connection.Stop(), ShouldBeNil)
+
+		So(connection.Delayed(), ShouldEqual, 0)
+		So(len(connection.DeadLetters()), ShouldEqual, 1)
+		So(connection.DeadLetters()[0].Topic(), ShouldEqual, "switch")
+	})
+}
+
+// TestMqttBase64PayloadEncoding - Ensure that base64 payloads are decoded
+// before event is built and invalid ones are dropped
+func TestMqttBase64PayloadEncoding(t *testing.T) {
+
+	Convey("Base64 Payloads Are Decoded", t, func() {
+		connection := testMqtt("base64-payload-encoding", withConnection("payloadEncoding", mqtt.Base64PayloadEncoding))
+
+		for _, payload := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte(TestMsgTrigger))} {
+			connection.BrokerHandler(nil, testMsg("switch", payload))
+		}
+
+		event, err := connection.WaitForMessage(100 * time.Millisecond)
+		So(err, ShouldBeNil)
+		So(string(event.Payload()), ShouldEqual, TestMsgTrigger)
+
+		_, err = connection.WaitForMessage(50 * time.Millisecond)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+// TestMqttConnValues - Ensure that events carry connection context values they
+// were emitted with
+func TestMqttConnValues(t *testing.T) {
+
+	Convey("Events Carry Connection Values", t, func() {
+		connection := testMqtt("conn-values", withConnection())
+		connection.WithValue("tenant", "tenant-a")
+		connection.BrokerHandler(nil, testMsg("switch", TestMsgTrigger))
+		connection.WithValue("tenant", "tenant-b")
+
+		event, err := connection.WaitForMessage(100 * time.Millisecond)
+		So(err, ShouldBeNil)
+		So(event.ConnValue("tenant"), ShouldEqual, "tenant-a")
+		So(event.ConnValue("unknown"), ShouldBeNil)
+	})
+}
+
+// TestMqttNameTemplate - Ensure that adapters built out of one name template get
+// their own configuration and that duplicate names are rejected
+func TestMqttNameTemplate(t *testing.T) {
+	logger := logging.New(map[string]interface{}{})
+
+	Convey("Template Adapters Do Not Share Configuration", t, func() {
+		west := withConnection("clientId", "template-west")
+		west["tags"] = map[string]interface{}{"region": "west"}
+
+		east := withConnection("clientId", "template-east")
+		east["tags"] = map[string]interface{}{"region": "east"}
+
+		westAdapter, err := mqtt.NewAdapter("template-{region}", west, logger)
+		So(err, ShouldBeNil)
+
+		eastAdapter, err := mqtt.NewAdapter("template-{region}", east, logger)
+		So(err, ShouldBeNil)
+
+		So(westAdapter.Name(), ShouldEqual, "template-west")
+		So(eastAdapter.Name(), ShouldEqual, "template-east")
+		So(westAdapter.(*mqtt.Connection).GetBrokerClientID(), ShouldEqual, "template-west")
+		So(eastAdapter.(*mqtt.Connection).GetBrokerClientID(), ShouldEqual, "template-east")
+
+	})
+
+	Convey("Adapter Is Rebuilt Under The Same Name", t, func() {
+		first, err := mqtt.NewAdapter("rebuilt", withConnection("clientId", "rebuilt-first"), logger)
+		So(err, ShouldBeNil)
+		So(first.Stop(), ShouldBeNil)
+
+		second, err := mqtt.NewAdapter("rebuilt", withConnection("clientId", "rebuilt-second"), logger)
+		So(err, ShouldBeNil)
+
+		So(second.Name(), ShouldEqual, "rebuilt")
+		So(second.(*mqtt.Connection).GetBrokerClientID(), ShouldEqual, "rebuilt-second")
+		So(first.(*mqtt.Connection).GetBrokerClientID(), ShouldEqual, "rebuilt-first")
+	})
+}
+
+// TestMqttClone - Ensure that clones get unique names and client ids while the
+// original configuration stays untouched
+func TestMqttClone(t *testing.T) {
+
+	Convey("Clone Gets Suffixed Name And Client Id", t, func() {
+		connection := testMqtt("clone", withConnection("clientId", "clone-client"))
+
+		clone, err := connection.Clone()
+		So(err, ShouldBeNil)
+		So(clone.Name(), ShouldEqual, "clone-1")
+		So(clone.(*mqtt.Connection).GetBrokerClientID(), ShouldEqual, "clone-client-1")
+		So(connection.GetBrokerClientID(), ShouldEqual, "clone-client")
+	})
+}
+
+// TestMqttBrokerLogging - Ensure that paho loggers are restored once all
+// connections forwarding them are stopped
+func TestMqttBrokerLogging(t *testing.T) {
+
+	Convey("Paho Loggers Are Restored On Stop", t, func() {
+		original := MQTT.DEBUG
+
+		first := testMqtt("broker-debug-first", withConnection("brokerDebug", true))
+		second := testMqtt("broker-debug-second", withConnection("brokerDebug", true))
+
+		first.SetupBrokerLogging()
+		second.SetupBrokerLogging()
+		So(MQTT.DEBUG, ShouldNotEqual, original)
+
+		So(first.Stop(), ShouldBeNil)
+		So(MQTT.DEBUG, ShouldNotEqual, original)
+
+		So(second.Stop(), ShouldBeNil)
+		So(MQTT.DEBUG, ShouldEqual, original)
+	})
+}
+
+// TestMqttReconnectStorm - Ensure that storm callback fires once per storm
+func TestMqttReconnectStorm(t *testing.T) {
+
+	Convey("Storm Is Reported Once", t, func() {
+		connection := testMqtt("reconnect-storm", withConnection("reconnectStormThreshold", 3))
+
+		storms := 0
+		connection.OnReconnectStorm(func(count int, window time.Duration) { storms++ })
+
+		for i := 0; i < 5; i++ {
+			connection.ConnectionLostHandler(nil, fmt.Errorf("connection reset"))
+		}
+
+		So(storms, ShouldEqual, 1)
+	})
+}
+
+// TestMqttSlowConsumer - Ensure that slow consumer callback fires once buffer
+// stays above watermark
+func TestMqttSlowConsumer(t *testing.T) {
+
+	Convey("Slow Consumer Is Reported Once", t, func() {
+		conf := withConnection("bufferSize", 4, "slowConsumerWatermark", 2, "slowConsumerDuration", "1ms")
+		connection := testMqtt("slow-consumer", conf)
+
+		slow := 0
+		connection.OnSlowConsumer(func(depth int, duration time.Duration) { slow++ })
+
+		for i := 0; i < 4; i++ {
+			connection.BrokerHandler(nil, testMsg("powerunit-io-bridge", TestMsgTrigger))
+			time.Sleep(2 * time.Millisecond)
+		}
+
+		So(slow, ShouldEqual, 1)
+	})
+}
+
+// TestMqttDeadLetterTopicLoop - Ensure that dead letter topic matched by own
+// subscription is rejected
+func TestMqttDeadLetterTopicLoop(t *testing.T) {
+
+	Convey("Dead Letter Topic Must Not Be Subscribed", t, func() {
+		looping := testMqtt("dead-letter-loop", withConnection("topic", "devices/#", "topicPrefix", "tenant", "deadLetterTopic", "devices/failed"))
+		So(looping.Validate(), ShouldNotBeNil)
+
+		separate := testMqtt("dead-letter-separate", withConnection("topic", "devices/#", "topicPrefix", "tenant", "deadLetterTopic", "failed/devices"))
+		So(separate.Validate(), ShouldBeNil)
+	})
+}
+
+// TestMqttValidateConfigs - Ensure that every invalid worker config is reported
+func TestMqttValidateConfigs(t *testing.T) {
+
+	Convey("Invalid Configs Are Reported By Index", t, func() {
+		valid := config.Config{Config: map[string]interface{}{"name": "valid", "connection": TestMqttConnection}}
+		invalid := config.Config{Config: withConnection("network", "udp")}
+
+		errs := mqtt.ValidateConfigs([]*config.Config{&valid, &invalid, nil})
+		So(errs, ShouldHaveLength, 2)
+		So(errs[0].Error(), ShouldContainSubstring, "(index: 1)")
+		So(errs[1].Error(), ShouldContainSubstring, "(index: 2)")
+	})
+}
+
+// testBrokerPacket - Will write raw mqtt packet (body shorter than 128 bytes)
+func testBrokerPacket(conn net.Conn, header byte, body []byte) {
+	conn.Write(append([]byte{header, byte(len(body))}, body...))
+}
+
+// testBrokerRead - Will read raw mqtt packet (body shorter than 128 bytes)
+func testBrokerRead(r *bufio.Reader) (byte, []byte, error) {
+	header, _ := r.ReadByte()
+	length, err := r.ReadByte()
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	body := make([]byte, length)
+	_, err = io.ReadFull(r, body)
+
+	return header, body, err
+}
+
+// testBrokerClient - Will connect raw mqtt client to the test broker
+func testBrokerClient(addr string) (net.Conn, *bufio.Reader) {
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	So(err, ShouldBeNil)
+
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	testBrokerPacket(conn, 0x10, []byte{0, 4, 'M', 'Q', 'T', 'T', 4, 0x02, 0, 60, 0, 1, 'c'})
+
+	r := bufio.NewReader(conn)
+	header, _, err := testBrokerRead(r)
+	So(err, ShouldBeNil)
+	So(header, ShouldEqual, 0x20)
+
+	return conn, r
+}
+
+// TestMqttTestBroker - Ensure that embedded test broker acknowledges
+// subscriptions and delivers live and retained messages
+func TestMqttTestBroker(t *testing.T) {
+
+	Convey("Live And Retained Messages Are Delivered", t, func() {
+		addr, stop := mqtttest.NewBroker(t)
+		defer stop()
+
+		publisher, _ := testBrokerClient(addr)
+		defer publisher.Close()
+
+		subscriber, r := testBrokerClient(addr)
+		defer subscriber.Close()
+
+		testBrokerPacket(subscriber, 0x82, []byte{0, 1, 0, 9, 'd', 'e', 'v', 'i', 'c', 'e', 's', '/', '#', 1})
+
+		header, body, err := testBrokerRead(r)
+		So(err, ShouldBeNil)
+		So(header, ShouldEqual, 0x90)
+		So(body[2], ShouldEqual, 1)
+
+		// Retained qos 0 publish on devices/a with payload "on"
+		testBrokerPacket(publisher, 0x31, []byte{0, 9, 'd', 'e', 'v', 'i', 'c', 'e', 's', '/', 'a', 'o', 'n'})
+
+		header, body, err = testBrokerRead(r)
+		So(err, ShouldBeNil)
+		So(header>>4, ShouldEqual, 3)
+		So(string(body), ShouldEndWith, "devices/aon")
+
+		late, lr := testBrokerClient(addr)
+		defer late.Close()
+
+		testBrokerPacket(late, 0x82, []byte{0, 1, 0, 9, 'd', 'e', 'v', 'i', 'c', 'e', 's', '/', '+', 0})
+		testBrokerRead(lr)
+
+		header, body, err = testBrokerRead(lr)
+		So(err, ShouldBeNil)
+		So(header, ShouldEqual, 0x31)
+		So(string(body), ShouldEndWith, "on")
+	})
+}
+
+// TestMqttTestBrokerQoS2 - Ensure that embedded test broker completes QoS 2
+// flows in both directions
+func TestMqttTestBrokerQoS2(t *testing.T) {
+
+	Convey("QoS 2 Publishes Are Completed", t, func() {
+		addr, stop := mqtttest.NewBroker(t)
+		defer stop()
+
+		client, r := testBrokerClient(addr)
+		defer client.Close()
+
+		testBrokerPacket(client, 0x82, []byte{0, 1, 0, 1, 'q', 2})
+
+		header, body, err := testBrokerRead(r)
+		So(err, ShouldBeNil)
+		So(header, ShouldEqual, 0x90)
+		So(body[2], ShouldEqual, 2)
+
+		// Qos 2 publish on q with packet id 7 and payload "on"
+		testBrokerPacket(client, 0x34, []byte{0, 1, 'q', 0, 7, 'o', 'n'})
+
+		received := map[byte][]byte{}
+		for i := 0; i < 2; i++ {
+			header, body, err = testBrokerRead(r)
+			So(err, ShouldBeNil)
+			received[header] = body
+		}
+
+		So(received[0x50], ShouldResemble, []byte{0, 7})
+		So(received, ShouldContainKey, byte(0x34))
+
+		delivered := received[0x34]
+		id := delivered[len(delivered)-4 : len(delivered)-2]
+
+		testBrokerPacket(client, 0x50, id)
+
+		header, body, err = testBrokerRead(r)
+		So(err, ShouldBeNil)
+		So(header, ShouldEqual, 0x62)
+		So(body, ShouldResemble, id)
+
+		testBrokerPacket(client, 0x62, []byte{0, 7})
+
+		header, body, err = testBrokerRead(r)
+		So(err, ShouldBeNil)
+		So(header, ShouldEqual, 0x70)
+		So(body, ShouldResemble, []byte{0, 7})
+	})
+}
+
+// TestMqttTestBrokerConnection - Ensure that started connection subscribes,
+// receives messages through embedded test broker and reconnects once broker
+// comes back
+func TestMqttTestBrokerConnection(t *testing.T) {
+
+	Convey("Connection Receives Messages And Reconnects", t, func() {
+		addr, stop := mqtttest.NewBroker(t)
+		defer func() { stop() }()
+
+		connection := testMqtt("test-broker-connection", withConnection(
+			"address", addr,
+			"clientId", "test-broker-connection",
+			"reconnectInterval", "100ms",
+			"reconnectJitter", false,
+		))
+
+		So(connection.Start(make(chan bool)), ShouldBeNil)
+		defer connection.Stop()
+
+		So(connection.Publish("powerunit-io-bridge", 1, false, TestMsgTrigger), ShouldBeNil)
+
+		event, err := connection.WaitForMessage(2 * time.Second)
+		So(err, ShouldBeNil)
+		So(event.DeviceID, ShouldEqual, "bedroom-switch")
+
+		stop()
+		addr, stop = mqtttest.Listen(t, addr)
+
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) && (connection.Phase() != managers.PhaseConnected || connection.LastDisconnectReason() == nil) {
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		So(connection.Phase(), ShouldEqual, managers.PhaseConnected)
+		So(connection.LastDisconnectReason(), ShouldNotBeNil)
+
+		So(connection.Publish("powerunit-io-bridge", 1, false, TestMsgTrigger), ShouldBeNil)
+
+		event, err = connection.WaitForMessage(2 * time.Second)
+		So(err, ShouldBeNil)
+		So(event.DeviceID, ShouldEqual, "bedroom-switch")
+	})
+}
+
+// TestMqttReconnectFailed - Ensure that failed connection is revived by
+// Reconnect once broker becomes reachable
+func TestMqttReconnectFailed(t *testing.T) {
+
+	Convey("Failed Connection Reconnects", t, func() {
+		addr, stop := mqtttest.NewBroker(t)
+		stop()
+
+		connection := testMqtt("test-reconnect-failed", withConnection(
+			"address", addr,
+			"clientId", "test-reconnect-failed",
+			"maxConnectAttempts", 1,
+		))
+
+		So(connection.Start(make(chan bool)), ShouldEqual, mqtt.ErrBrokerUnreachable)
+		defer connection.Stop()
+
+		So(connection.Phase(), ShouldEqual, managers.PhaseFailed)
+		So(connection.Healthy(), ShouldBeFalse)
+
+		_, stop = mqtttest.Listen(t, addr)
+		defer stop()
+
+		So(connection.Reconnect(), ShouldBeNil)
+		So(connection.Healthy(), ShouldBeTrue)
+		So(connection.Reconnect(), ShouldNotBeNil)
+
+		So(connection.Publish("powerunit-io-bridge", 1, false, TestMsgTrigger), ShouldBeNil)
+
+		event, err := connection.WaitForMessage(2 * time.Second)
+		So(err, ShouldBeNil)
+		So(event.DeviceID, ShouldEqual, "bedroom-switch")
+	})
+}
+
+// TestMqttConcurrentRequests - Ensure that concurrent requests sharing response
+// topic each receive the response carrying their correlation id
+func TestMqttConcurrentRequests(t *testing.T) {
+
+	Convey("Requests On Shared Response Topic Are Routed By Correlation Id", t, func() {
+		addr, stop := mqtttest.NewBroker(t)
+		defer stop()
+
+		responder := testMqtt("test-requests-responder", withConnection(
+			"address", addr,
+			"clientId", "test-requests-responder",
+			"topic", "requests",
+			"bufferSize", 4,
+		))
+
+		So(responder.Start(make(chan bool)), ShouldBeNil)
+		defer responder.Stop()
+
+		requester := testMqtt("test-requests-requester", withConnection(
+			"address", addr,
+			"clientId", "test-requests-requester",
+			"topic", "requester",
+		))
+
+		So(requester.Start(make(chan bool)), ShouldBeNil)
+		defer requester.Stop()
+
+		request := func(device string) <-chan events.Event {
+			responses := make(chan events.Event, 1)
+
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+
+				event, _ := requester.Request(ctx, "requests", map[string]interface{}{
+					"type": "t", "device_id": device, "data": map[string]interface{}{"state": "on"},
+				}, "responses")
+
+				responses <- event
+			}()
+
+			return responses
+		}
+
+		first, second := request("first-switch"), request("second-switch")
+
+		received := []events.Event{}
+		for i := 0; i < 2; i++ {
+			event, err := responder.WaitForMessage(2 * time.Second)
+			So(err, ShouldBeNil)
+			received = append(received, event)
+		}
+
+		// Respond in reverse order so that routing can't rely on arrival
+		for i := len(received) - 1; i >= 0; i-- {
+			So(responder.Publish("responses", 1, false, fmt.Sprintf(
+				`{"type": "t", "device_id": "%s", "correlation_id": "%s", "data": {"state": "off"}}`,
+				received[i].DeviceID, received[i].CorrelationID,
+			)), ShouldBeNil)
+		}
+
+		So((<-first).DeviceID, ShouldEqual, "first-switch")
+		So((<-second).DeviceID, ShouldEqual, "second-switch")
+
+		third := request("third-switch")
+
+		event, err := responder.WaitForMessage(2 * time.Second)
+		So(err, ShouldBeNil)
+
+		So(responder.Publish("responses", 1, false, fmt.Sprintf(
+			`{"type": "t", "device_id": "%s", "correlation_id": "%s", "data": {"state": "off"}}`,
+			event.DeviceID, event.CorrelationID,
+		)), ShouldBeNil)
+
+		So((<-third).DeviceID, ShouldEqual, "third-switch")
+	})
+}
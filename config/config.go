@@ -5,11 +5,18 @@
 // Package config ...
 package config
 
-import "github.com/powerunit-io/platform/utils"
+import (
+	"fmt"
+	"time"
+
+	"github.com/powerunit-io/platform/utils"
+)
 
 // Config - Configuration manager helper designed to address configuration items
 type Config struct {
 	Config map[string]interface{}
+
+	sensitive []string
 }
 
 // Set - Will set value of requested key within configuration manager instance
@@ -33,7 +40,94 @@ func (c *Config) Get(key string) interface{} {
 	return c.Config[key]
 }
 
+// GetDuration - Retreive configuration value as duration. Go duration strings
+// ("10s", "2m") and bare numbers (seconds) are accepted. Default is returned in
+// case that key is missing or value cannot be parsed.
+func (c *Config) GetDuration(key string, def time.Duration) time.Duration {
+	if duration, ok := utils.AsDuration(c.Get(key)); ok {
+		return duration
+	}
+
+	return def
+}
+
+// GetTags - Retreive `tags` configuration map (region, environment, ...) as
+// labels. Non string values are formatted, empty map is returned when missing.
+func (c *Config) GetTags() map[string]string {
+	tags := map[string]string{}
+
+	if data, ok := utils.AsStringMap(c.Get("tags")); ok {
+		for key, value := range data {
+			tags[key] = fmt.Sprintf("%v", value)
+		}
+	}
+
+	return tags
+}
+
 // KeyExists - Check whenever key exists within configuration manager instance
 func (c *Config) KeyExists(key string) bool {
 	return utils.KeyInSlice(key, c.Config)
 }
+
+// MarkSensitive - Will mark keys as sensitive in addition to global SensitiveKeys.
+// Values of sensitive keys are masked by Redacted, Redact and String.
+func (c *Config) MarkSensitive(keys ...string) {
+	for _, key := range keys {
+		if !utils.StringInSlice(key, c.sensitive) {
+			c.sensitive = append(c.sensitive, key)
+		}
+	}
+}
+
+// IsSensitive - Check whenever value of the key should be masked
+func (c *Config) IsSensitive(key string) bool {
+	return utils.StringInSlice(key, SensitiveKeys) || utils.StringInSlice(key, c.sensitive)
+}
+
+// Redacted - Will return copy of configuration where all sensitive values
+// (including ones within nested maps and lists) are masked. Safe to be logged or exposed.
+func (c *Config) Redacted() map[string]interface{} {
+	return c.Redact(c.Config)
+}
+
+// Redact - Will recursively copy data replacing values of sensitive keys. Useful
+// for masking part of configuration (e.g. connection block) before logging it.
+func (c *Config) Redact(data map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(data))
+
+	for key, value := range data {
+		if c.IsSensitive(key) {
+			redacted[key] = RedactedValue
+			continue
+		}
+
+		redacted[key] = c.redactValue(value)
+	}
+
+	return redacted
+}
+
+// redactValue - Will redact maps nested in the value, including maps within
+// lists (e.g. list of brokers with credentials)
+func (c *Config) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return c.Redact(v)
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+
+		for i, item := range v {
+			redacted[i] = c.redactValue(item)
+		}
+
+		return redacted
+	}
+
+	return value
+}
+
+// String - Will return redacted representation of configuration
+func (c *Config) String() string {
+	return fmt.Sprintf("%v", c.Redacted())
+}
@@ -0,0 +1,70 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package config ...
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// ExpandEnv - Will return copy of configuration data where `${VAR}` and `$VAR`
+// references within string values (including nested maps and lists) are
+// replaced by environment variables. `$$` stands for literal `$`. Missing
+// variables expand to empty string unless StrictEnv is set, in which case
+// error listing them is returned.
+func ExpandEnv(data map[string]interface{}) (map[string]interface{}, error) {
+	missing := []string{}
+	expanded := expandMap(data, &missing)
+
+	if StrictEnv && len(missing) > 0 {
+		return nil, fmt.Errorf("Could not expand configuration as environment (variables: %v) are not set", missing)
+	}
+
+	return expanded, nil
+}
+
+// expandMap -
+func expandMap(data map[string]interface{}, missing *[]string) map[string]interface{} {
+	expanded := make(map[string]interface{}, len(data))
+
+	for key, value := range data {
+		expanded[key] = expandValue(value, missing)
+	}
+
+	return expanded
+}
+
+// expandValue -
+func expandValue(value interface{}, missing *[]string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return os.Expand(v, func(name string) string {
+			if name == "$" {
+				return "$"
+			}
+
+			env, ok := os.LookupEnv(name)
+
+			if !ok {
+				*missing = append(*missing, name)
+			}
+
+			return env
+		})
+	case map[string]interface{}:
+		return expandMap(v, missing)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+
+		for i, item := range v {
+			list[i] = expandValue(item, missing)
+		}
+
+		return list
+	}
+
+	return value
+}
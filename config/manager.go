@@ -7,6 +7,7 @@ package config
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/powerunit-io/platform/utils"
 )
@@ -14,8 +15,15 @@ import (
 // ConfigManagers -
 var ConfigManager map[string]interface{}
 
+// configManagerLock - Guards ConfigManager as adapters are built (and rebuilt
+// on reload) concurrently
+var configManagerLock sync.RWMutex
+
 // ConfigManagerExists -
 func ConfigManagerExists(manager string) bool {
+	configManagerLock.RLock()
+	defer configManagerLock.RUnlock()
+
 	return utils.KeyInSlice(manager, ConfigManager)
 }
 
@@ -23,21 +31,36 @@ func ConfigManagerExists(manager string) bool {
 // error will be returned.
 func GetConfigManager(managerName string) (*Config, error) {
 
-	if !ConfigManagerExists(managerName) {
+	configManagerLock.RLock()
+	stored, ok := ConfigManager[managerName]
+	configManagerLock.RUnlock()
+
+	if !ok {
 		return nil, fmt.Errorf("Could not discover configuration (manager: %s). Forgot to load it?", managerName)
 	}
 
-	manager := ConfigManager[managerName].(Config)
+	manager := stored.(Config)
 	return &manager, nil
 }
 
 // SetConfigManager - Will create and assign new configuration manager based on
-// provided name and cofiguration data
+// provided name and cofiguration data. Environment variables referenced within
+// configuration values are expanded, see ExpandEnv
 func SetConfigManager(managerName string, configData map[string]interface{}) (*Config, error) {
 	if !ConfigManagerExists(managerName) {
-		ConfigManager[managerName] = Config{
-			Config: configData,
+		expanded, err := ExpandEnv(configData)
+
+		if err != nil {
+			return nil, fmt.Errorf("Could not set configuration (manager: %s) due to (err: %s)", managerName, err)
+		}
+
+		configManagerLock.Lock()
+		if _, ok := ConfigManager[managerName]; !ok {
+			ConfigManager[managerName] = Config{
+				Config: expanded,
+			}
 		}
+		configManagerLock.Unlock()
 	}
 
 	return GetConfigManager(managerName)
@@ -49,3 +72,31 @@ func SetConfigManager(managerName string, configData map[string]interface{}) (*C
 func NewConfigManager(managerName string, configData map[string]interface{}) (*Config, error) {
 	return SetConfigManager(managerName, configData)
 }
+
+// NewNamedConfigManager - Will expand name template (see ExpandName) against
+// provided configuration data and create configuration manager under the
+// expanded name, so connections built out of the same template never share
+// configuration. Expanded name is returned along with the manager. Manager
+// already stored under the expanded name is replaced, so adapter rebuilt under
+// the same name (reload, restart after Stop) gets its new configuration, while
+// adapters built earlier keep their own copy (see GetConfigManager).
+func NewNamedConfigManager(template string, configData map[string]interface{}) (*Config, string, error) {
+	expanded, err := ExpandEnv(configData)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("Could not set configuration (manager: %s) due to (err: %s)", template, err)
+	}
+
+	manager := Config{Config: expanded}
+	name, err := manager.ExpandName(template)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	configManagerLock.Lock()
+	ConfigManager[name] = manager
+	configManagerLock.Unlock()
+
+	return &manager, name, nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package config ...
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// nameMarker - `{key}` marker within name template
+var nameMarker = regexp.MustCompile(`\{([A-Za-z0-9_.-]+)\}`)
+
+// ExpandName - Will resolve `{key}` markers within name template (e.g.
+// `mqtt-{region}-{deviceClass}`) against tags first and top level scalar
+// configuration values second. Names without markers are returned as is. Error
+// listing markers which could not be resolved is returned.
+func (c *Config) ExpandName(template string) (string, error) {
+	tags := c.GetTags()
+	missing := []string{}
+
+	name := nameMarker.ReplaceAllStringFunc(template, func(marker string) string {
+		key := nameMarker.FindStringSubmatch(marker)[1]
+
+		if tag, ok := tags[key]; ok {
+			return tag
+		}
+
+		switch value := c.Get(key).(type) {
+		case string, bool, int, int64, float64:
+			return fmt.Sprintf("%v", value)
+		}
+
+		missing = append(missing, key)
+		return marker
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("Could not expand (name: %s) as (markers: %v) are neither tags nor configuration values", template, missing)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,28 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package config ...
+package config
+
+import "time"
+
+var (
+	// SensitiveKeys - Configuration keys whose values are masked whenever
+	// configuration is exposed (debug output, listings, etc.)
+	SensitiveKeys = []string{"password", "secret", "token"}
+
+	// StrictEnv - When set, referencing environment variable which is not set
+	// within configuration is an error instead of expanding to empty string
+	StrictEnv = false
+
+	// RedactedValue - Value used instead of sensitive configuration values
+	RedactedValue = "********"
+
+	// WatchInterval - How often watched configuration file is checked for changes
+	WatchInterval = time.Second
+
+	// WatchDebounce - How long watched file has to stay unchanged before it's
+	// reparsed, so editors writing file in several steps trigger single reload
+	WatchDebounce = 500 * time.Millisecond
+)
@@ -0,0 +1,98 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package config ...
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// LoadFile - Will parse json configuration file. Environment variables
+// referenced within configuration values are expanded, see ExpandEnv
+func LoadFile(path string) (*Config, error) {
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not read configuration (file: %s) due to (err: %s)", path, err)
+	}
+
+	data := map[string]interface{}{}
+
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("Could not parse configuration (file: %s) due to (err: %s)", path, err)
+	}
+
+	expanded, err := ExpandEnv(data)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not load configuration (file: %s) due to (err: %s)", path, err)
+	}
+
+	return &Config{Config: expanded}, nil
+}
+
+// WatchFile - Will watch configuration file and invoke onChange with reparsed
+// configuration whenever it changes. File is polled every WatchInterval and
+// changes are debounced by WatchDebounce. Content which cannot be parsed is
+// skipped until the file changes again. Returned function stops watching.
+func WatchFile(path string, onChange func(*Config)) (func(), error) {
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not watch configuration (file: %s) due to (err: %s)", path, err)
+	}
+
+	stop := make(chan bool)
+
+	go func() {
+		ticker := time.NewTicker(WatchInterval)
+		defer ticker.Stop()
+
+		seen := info.ModTime()
+		size := info.Size()
+
+		var changedAt time.Time
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			current, err := os.Stat(path)
+
+			if err != nil {
+				continue
+			}
+
+			if !current.ModTime().Equal(seen) || current.Size() != size {
+				seen = current.ModTime()
+				size = current.Size()
+				changedAt = time.Now()
+				continue
+			}
+
+			if changedAt.IsZero() || time.Since(changedAt) < WatchDebounce {
+				continue
+			}
+
+			changedAt = time.Time{}
+
+			if config, err := LoadFile(path); err == nil {
+				onChange(config)
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() { once.Do(func() { close(stop) }) }, nil
+}
@@ -0,0 +1,139 @@
+package platform
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/powerunit-io/platform/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestConfigRedaction - Ensure that sensitive values (global or marked) are
+// masked within nested configuration and that String() never exposes them
+func TestConfigRedaction(t *testing.T) {
+	cnf := config.Config{Config: map[string]interface{}{
+		"name": "redaction-test",
+		"uri":  "user:secret@tcp(localhost:3306)/db",
+		"connection": map[string]interface{}{
+			"username": "user",
+			"password": "broker-password",
+			"address": []interface{}{
+				map[string]interface{}{"address": "broker:1883", "password": "listed-password"},
+			},
+		},
+	}}
+
+	cnf.MarkSensitive("uri")
+
+	Convey("Sensitive Values Are Masked", t, func() {
+		redacted := cnf.Redacted()
+		So(redacted["name"], ShouldEqual, "redaction-test")
+		So(redacted["uri"], ShouldEqual, config.RedactedValue)
+		So(redacted["connection"].(map[string]interface{})["username"], ShouldEqual, "user")
+		So(redacted["connection"].(map[string]interface{})["password"], ShouldEqual, config.RedactedValue)
+
+		listed := redacted["connection"].(map[string]interface{})["address"].([]interface{})[0].(map[string]interface{})
+		So(listed["address"], ShouldEqual, "broker:1883")
+		So(listed["password"], ShouldEqual, config.RedactedValue)
+	})
+
+	Convey("Original Config Is Untouched", t, func() {
+		So(cnf.Get("uri"), ShouldEqual, "user:secret@tcp(localhost:3306)/db")
+	})
+
+	Convey("String Does Not Leak Secrets", t, func() {
+		So(cnf.String(), ShouldNotContainSubstring, "broker-password")
+		So(cnf.String(), ShouldNotContainSubstring, "secret@")
+	})
+}
+
+// TestConfigEnvExpansion - Ensure that environment variables are expanded within
+// nested configuration and that missing ones are reported in strict mode
+func TestConfigEnvExpansion(t *testing.T) {
+	os.Setenv("PU_TEST_BROKER_PASSWORD", "from-env")
+	defer os.Unsetenv("PU_TEST_BROKER_PASSWORD")
+
+	data := map[string]interface{}{
+		"connection": map[string]interface{}{
+			"password": "${PU_TEST_BROKER_PASSWORD}",
+			"username": "user$$name",
+		},
+		"topics": []interface{}{"$PU_TEST_BROKER_PASSWORD/telemetry"},
+	}
+
+	Convey("References Are Expanded", t, func() {
+		expanded, err := config.ExpandEnv(data)
+		So(err, ShouldBeNil)
+		So(expanded["connection"].(map[string]interface{})["password"], ShouldEqual, "from-env")
+		So(expanded["connection"].(map[string]interface{})["username"], ShouldEqual, "user$name")
+		So(expanded["topics"].([]interface{})[0], ShouldEqual, "from-env/telemetry")
+	})
+
+	Convey("Missing Variables Fail In Strict Mode", t, func() {
+		config.StrictEnv = true
+		defer func() { config.StrictEnv = false }()
+
+		_, err := config.ExpandEnv(map[string]interface{}{"password": "${PU_TEST_MISSING}"})
+		So(err, ShouldNotBeNil)
+	})
+}
+
+// TestConfigWatchFile - Ensure that watched file is reparsed once changed
+func TestConfigWatchFile(t *testing.T) {
+	config.WatchInterval = 10 * time.Millisecond
+	config.WatchDebounce = 20 * time.Millisecond
+
+	file, _ := ioutil.TempFile("", "config-watch")
+	defer os.Remove(file.Name())
+
+	file.WriteString(`{"service_name": "before"}`)
+	file.Close()
+
+	changes := make(chan *config.Config, 1)
+	stop, err := config.WatchFile(file.Name(), func(cnf *config.Config) { changes <- cnf })
+
+	Convey("File Is Watched", t, func() {
+		So(err, ShouldBeNil)
+	})
+
+	defer stop()
+
+	ioutil.WriteFile(file.Name(), []byte(`{"service_name": "after", "service_version": 2}`), 0644)
+
+	Convey("Change Is Picked Up", t, func() {
+		select {
+		case cnf := <-changes:
+			So(cnf.Get("service_name"), ShouldEqual, "after")
+		case <-time.After(2 * time.Second):
+			t.Errorf("Configuration change was not picked up")
+		}
+	})
+}
+
+// TestConfigExpandName - Ensure that name templates are resolved against tags
+// and configuration while literal names are kept
+func TestConfigExpandName(t *testing.T) {
+	cnf := config.Config{Config: map[string]interface{}{
+		"deviceClass": "meter",
+		"tags":        map[string]interface{}{"region": "eu-west"},
+	}}
+
+	Convey("Template Is Expanded", t, func() {
+		name, err := cnf.ExpandName("mqtt-{region}-{deviceClass}")
+		So(err, ShouldBeNil)
+		So(name, ShouldEqual, "mqtt-eu-west-meter")
+	})
+
+	Convey("Literal Name Is Kept", t, func() {
+		name, err := cnf.ExpandName("mqtt-main")
+		So(err, ShouldBeNil)
+		So(name, ShouldEqual, "mqtt-main")
+	})
+
+	Convey("Unknown Marker Is Rejected", t, func() {
+		_, err := cnf.ExpandName("mqtt-{zone}")
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,393 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package file ...
+package file
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/powerunit-io/platform/config"
+	"github.com/powerunit-io/platform/connections"
+	"github.com/powerunit-io/platform/events"
+	"github.com/powerunit-io/platform/logging"
+	"github.com/powerunit-io/platform/managers"
+	"github.com/powerunit-io/platform/utils"
+
+	MQTT "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
+)
+
+// Connection - Replays captured messages from the file as events
+type Connection struct {
+	*logging.Logger
+	*config.Config
+	managers.PhaseTracker
+
+	events   chan events.Event
+	done     chan bool
+	stop     chan bool
+	stopOnce sync.Once
+	routines sync.WaitGroup
+
+	startedAt   time.Time
+	replayed    int
+	replayedErr error
+	lock        sync.Mutex
+}
+
+// Start - Will open capture files and start replay in background. Replay stops
+// at the end of the captures, on Stop or once done is signalled.
+func (c *Connection) Start(done chan bool) error {
+	next, closeFiles, err := c.openReplay()
+
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	c.startedAt = time.Now()
+	c.lock.Unlock()
+
+	c.done = done
+	c.SetPhase(managers.PhaseConnected)
+
+	c.Info(
+		"Starting replay (worker: %s) of (files: %v) - (format: %s) - (order: %s) - (timing: %t) ...",
+		c.Name(), c.GetPaths(), c.GetFormat(), c.GetOrder(), c.GetTiming(),
+	)
+
+	c.routines.Add(1)
+	go c.replay(next, closeFiles)
+
+	return nil
+}
+
+// replay - Will emit records one by one, honoring recorded timing if configured.
+// Timing follows the latest timestamp seen, so records streamed out of timestamp
+// order (file order over several captures) are not delayed.
+func (c *Connection) replay(next reader, closeFiles func()) {
+	defer c.routines.Done()
+	defer c.SetPhase(managers.PhaseStopped)
+	defer closeFiles()
+
+	var previous time.Time
+
+	for {
+		rec, err := next()
+
+		if err == io.EOF {
+			c.Info("Replay (worker: %s) of (files: %v) finished (records: %d)", c.Name(), c.GetPaths(), c.Replayed())
+			return
+		}
+
+		if err != nil {
+			c.Error("Could not continue replay (worker: %s) of (files: %v) due to (err: %s)", c.Name(), c.GetPaths(), err)
+			c.lock.Lock()
+			c.replayedErr = err
+			c.lock.Unlock()
+			return
+		}
+
+		if c.GetTiming() && !previous.IsZero() && !rec.timestamp.IsZero() {
+			gap := rec.timestamp.Sub(previous)
+
+			if gap > MaxTimingGap {
+				gap = MaxTimingGap
+			}
+
+			if gap > 0 {
+				select {
+				case <-time.After(gap):
+				case <-c.stop:
+					return
+				case <-c.done:
+					return
+				}
+			}
+		}
+
+		if rec.timestamp.After(previous) {
+			previous = rec.timestamp
+		}
+
+		select {
+		case <-c.stop:
+			return
+		case <-c.done:
+			return
+		default:
+		}
+
+		c.emit(&message{topic: rec.topic, payload: rec.payload}, rec.sequence, rec.source)
+
+		c.lock.Lock()
+		c.replayed++
+		c.lock.Unlock()
+	}
+}
+
+// Emit - Will build event out of the message and push it to the events channel
+func (c *Connection) Emit(msg MQTT.Message) {
+	c.emit(msg, 0, "")
+}
+
+// emit - Will build event out of the message carrying recorded sequence and
+// source of the record
+func (c *Connection) emit(msg MQTT.Message, sequence uint64, source string) {
+	event, err := events.NewEvent(msg)
+
+	if err != nil {
+		c.Error("Could not handle replayed event due to (err: %s)", err)
+		event.Release()
+		return
+	}
+
+	event.Sequence, event.Source = sequence, source
+
+	select {
+	case c.events <- event:
+	case <-c.stop:
+		event.Release()
+	}
+}
+
+// Replayed - Will return number of records replayed so far
+func (c *Connection) Replayed() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.replayed
+}
+
+// Err - Will return error which interrupted replay, if any
+func (c *Connection) Err() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.replayedErr
+}
+
+// Wait - Will block until replay goroutine exited
+func (c *Connection) Wait() {
+	c.routines.Wait()
+}
+
+// DrainEvents - Will return event chan back for future processing by workers
+func (c *Connection) DrainEvents() chan events.Event {
+	return c.events
+}
+
+// WaitForMessage - Will return next event or error once timeout expires.
+// Intended for tests.
+func (c *Connection) WaitForMessage(timeout time.Duration) (events.Event, error) {
+	return events.WaitFor(c.DrainEvents(), timeout)
+}
+
+// WaitForMessageMatching - Will return next event satisfying the predicate or
+// error once timeout expires. Events not matching are consumed and released.
+// Intended for tests.
+func (c *Connection) WaitForMessageMatching(pred func(events.Event) bool, timeout time.Duration) (events.Event, error) {
+	return events.WaitForMatching(c.DrainEvents(), pred, timeout)
+}
+
+// Validate -
+func (c *Connection) Validate() error {
+	c.Info("Validating file replay configuration for (worker: %q)", c.Name())
+
+	data, ok := utils.AsStringMap(c.Config.Get("connection"))
+
+	if !ok {
+		return fmt.Errorf(
+			"Could not validate file worker as connection interface is missing or not a map (entry: %T)",
+			c.Config.Get("connection"),
+		)
+	}
+
+	if err := c.validatePaths(data); err != nil {
+		return err
+	}
+
+	if format, ok := data["format"]; ok {
+		if !utils.StringInSlice(fmt.Sprintf("%v", format), AvailableFormats) {
+			return fmt.Errorf(
+				"Could not validate file worker as connection format is not valid. (format: %v) - (available_formats: %v)",
+				format, AvailableFormats,
+			)
+		}
+	}
+
+	if order, ok := data["order"]; ok {
+		if !utils.StringInSlice(fmt.Sprintf("%v", order), AvailableOrders) {
+			return fmt.Errorf(
+				"Could not validate file worker as connection order is not valid. (order: %v) - (available_orders: %v)",
+				order, AvailableOrders,
+			)
+		}
+	}
+
+	if timing, ok := data["timing"]; ok {
+		if _, ok := timing.(bool); !ok {
+			return fmt.Errorf("Could not validate file worker as connection timing is not boolean. (timing: %v)", timing)
+		}
+	}
+
+	return nil
+}
+
+// validatePaths - will ensure that `path` is readable capture file or non empty
+// list of them
+func (c *Connection) validatePaths(data map[string]interface{}) error {
+	var paths []interface{}
+
+	switch path := data["path"].(type) {
+	case string:
+		paths = []interface{}{path}
+	case []interface{}:
+		paths = path
+	}
+
+	if len(paths) == 0 {
+		return fmt.Errorf("Could not validate file worker as connection path is not set. (connection_data: %q)", c.Redact(data))
+	}
+
+	for _, entry := range paths {
+		path, ok := entry.(string)
+
+		if !ok || path == "" {
+			return fmt.Errorf("Could not validate file worker as connection (path: %v) is not valid", entry)
+		}
+
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			return fmt.Errorf("Could not validate file worker as connection (path: %s) is not readable file", path)
+		}
+	}
+
+	return nil
+}
+
+// connection - will return connection config block or empty one in case that
+// it's missing or malformed (Validate reports that)
+func (c *Connection) connection() map[string]interface{} {
+	if connection, ok := utils.AsStringMap(c.Config.Get("connection")); ok {
+		return connection
+	}
+
+	return map[string]interface{}{}
+}
+
+// GetPath - will return path of the (first) capture file
+func (c *Connection) GetPath() string {
+	if paths := c.GetPaths(); len(paths) > 0 {
+		return paths[0]
+	}
+
+	return ""
+}
+
+// GetPaths - will return paths of capture files. Path is either single path or
+// list of them (e.g. captures of several connections or rotated captures),
+// replayed together in `order`.
+func (c *Connection) GetPaths() []string {
+	paths := []string{}
+
+	switch path := c.connection()["path"].(type) {
+	case string:
+		paths = append(paths, path)
+	case []interface{}:
+		for _, entry := range path {
+			if value, ok := entry.(string); ok {
+				paths = append(paths, value)
+			}
+		}
+	}
+
+	return paths
+}
+
+// GetOrder - will return order records are replayed in. Defaults to file,
+// which streams captures without loading them up front. Sequence order restores
+// the order messages were recorded in across captures.
+func (c *Connection) GetOrder() string {
+	if order, ok := utils.AsString(c.connection()["order"]); ok {
+		return order
+	}
+
+	return FileOrder
+}
+
+// GetFormat - will return capture format. Defaults to ndjson.
+func (c *Connection) GetFormat() string {
+	if format, ok := utils.AsString(c.connection()["format"]); ok {
+		return format
+	}
+
+	return NDJSONFormat
+}
+
+// GetTiming - will return whenever original inter-message timing (recorded
+// timestamps) is honored. Defaults to false, replaying as fast as consumed.
+func (c *Connection) GetTiming() bool {
+	timing, _ := c.connection()["timing"].(bool)
+	return timing
+}
+
+// Name -
+func (c *Connection) Name() string {
+	name, _ := utils.AsString(c.Config.Get("name"))
+	return name
+}
+
+// Kind -
+func (c *Connection) Kind() string {
+	return Kind
+}
+
+// Status - Will return connected status while replay is running
+func (c *Connection) Status() string {
+	if c.Phase() == managers.PhaseConnected {
+		return managers.StatusConnected
+	}
+
+	return managers.StatusStopped
+}
+
+// Describe - Will return snapshot of connection configuration and state
+func (c *Connection) Describe() connections.ConnectionInfo {
+	info := connections.ConnectionInfo{
+		Kind:   c.Kind(),
+		Name:   c.Name(),
+		Broker: c.GetPath(),
+		Status: c.Status(),
+		Phase:  c.Phase(),
+		Config: c.Redacted(),
+	}
+
+	if info.Status == managers.StatusConnected {
+		c.lock.Lock()
+		info.Connected = true
+		info.Uptime = time.Since(c.startedAt)
+		c.lock.Unlock()
+	}
+
+	return info
+}
+
+// Adapter -
+func (c *Connection) Adapter() interface{} {
+	return &c
+}
+
+// Stop - Will halt replay
+func (c *Connection) Stop() error {
+	c.Warning("Stopping file replay (worker: %s) ...", c.Name())
+
+	c.stopOnce.Do(func() { close(c.stop) })
+	c.SetPhase(managers.PhaseStopped)
+
+	return nil
+}
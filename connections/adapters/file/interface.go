@@ -0,0 +1,47 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package file ...
+package file
+
+import (
+	"time"
+
+	"github.com/powerunit-io/platform/config"
+	"github.com/powerunit-io/platform/connections"
+	"github.com/powerunit-io/platform/events"
+	"github.com/powerunit-io/platform/logging"
+	"github.com/powerunit-io/platform/utils"
+)
+
+// Adapter -
+type Adapter interface {
+	connections.Connection
+
+	DrainEvents() chan events.Event
+	WaitForMessage(timeout time.Duration) (events.Event, error)
+	WaitForMessageMatching(pred func(events.Event) bool, timeout time.Duration) (events.Event, error)
+	Replayed() int
+	Err() error
+	Wait()
+}
+
+// NewAdapter -
+func NewAdapter(n string, conf map[string]interface{}, logger *logging.Logger) (Adapter, error) {
+
+	cnf, name, err := config.NewNamedConfigManager(n, conf)
+
+	if err != nil {
+		logger.Error("Failed to configure file configuration manager for (manager: %s) (error: %s)", n, err)
+		return nil, err
+	}
+
+	cnf.Set("name", name)
+
+	concurrency := utils.GetConcurrencyCount("PU_GO_MAX_CONCURRENCY")
+
+	return Adapter(&Connection{
+		Logger: logger, Config: cnf, events: make(chan events.Event, concurrency), stop: make(chan bool),
+	}), nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package file ...
+package file
+
+import "time"
+
+// record - Single captured message
+type record struct {
+	timestamp time.Time
+	sequence  uint64
+	source    string
+	topic     string
+	payload   []byte
+}
+
+// message - Replayed record satisfying MQTT.Message so events can be built out
+// of it the same way as out of mqtt messages
+type message struct {
+	topic   string
+	payload []byte
+}
+
+// Duplicate -
+func (m *message) Duplicate() bool {
+	return false
+}
+
+// Qos -
+func (m *message) Qos() byte {
+	return 0
+}
+
+// Retained -
+func (m *message) Retained() bool {
+	return false
+}
+
+// Topic -
+func (m *message) Topic() string {
+	return m.topic
+}
+
+// MessageID -
+func (m *message) MessageID() uint16 {
+	return 0
+}
+
+// Payload -
+func (m *message) Payload() []byte {
+	return m.payload
+}
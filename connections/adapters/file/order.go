@@ -0,0 +1,122 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package file ...
+package file
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// openReplay - Will open all captures and return reader of their records in
+// `order` together with function closing the captures. File order streams
+// records; sequence and timestamp orders load all records up front so they
+// can be sorted, in which case decoding error fails right away.
+func (c *Connection) openReplay() (reader, func(), error) {
+	files := []*os.File{}
+	readers := []reader{}
+
+	closeFiles := func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}
+
+	for _, path := range c.GetPaths() {
+		file, err := os.Open(path)
+
+		if err != nil {
+			closeFiles()
+			return nil, nil, fmt.Errorf("Could not open (file: %s) for replay (worker: %s) due to (err: %s)", path, c.Name(), err)
+		}
+
+		files = append(files, file)
+		readers = append(readers, newReader(file, c.GetFormat()))
+	}
+
+	next := chainReaders(readers)
+
+	if c.GetOrder() == FileOrder {
+		return next, closeFiles, nil
+	}
+
+	defer closeFiles()
+
+	records := []record{}
+
+	for {
+		rec, err := next()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("Could not load replay (worker: %s) records due to (err: %s)", c.Name(), err)
+		}
+
+		records = append(records, rec)
+	}
+
+	sortRecords(records, c.GetOrder())
+
+	return sliceReader(records), func() {}, nil
+}
+
+// sortRecords - Will sort records by the order. Sequence is only monotonic
+// within single recording process, so sequence order merges captures by
+// (timestamp, source, sequence) while timestamp order sorts by timestamp alone.
+// Sort is stable, so records without timestamp and sequence (binary captures,
+// captures of older recorders) keep their relative order.
+func sortRecords(records []record, order string) {
+	sort.SliceStable(records, func(i, j int) bool {
+		if !records[i].timestamp.Equal(records[j].timestamp) {
+			return records[i].timestamp.Before(records[j].timestamp)
+		}
+
+		if order == TimestampOrder {
+			return false
+		}
+
+		if records[i].source != records[j].source {
+			return records[i].source < records[j].source
+		}
+
+		return records[i].sequence < records[j].sequence
+	})
+}
+
+// chainReaders - Will read records of readers one after another
+func chainReaders(readers []reader) reader {
+	return func() (record, error) {
+		for len(readers) > 0 {
+			rec, err := readers[0]()
+
+			if err != io.EOF {
+				return rec, err
+			}
+
+			readers = readers[1:]
+		}
+
+		return record{}, io.EOF
+	}
+}
+
+// sliceReader - Will read already loaded records
+func sliceReader(records []record) reader {
+	return func() (record, error) {
+		if len(records) == 0 {
+			return record{}, io.EOF
+		}
+
+		rec := records[0]
+		records = records[1:]
+
+		return rec, nil
+	}
+}
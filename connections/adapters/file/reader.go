@@ -0,0 +1,81 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package file ...
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/powerunit-io/platform/events"
+)
+
+// reader - Will return next record or io.EOF once there are no more records
+type reader func() (record, error)
+
+// newReader - Will return record reader for the format
+func newReader(r io.Reader, format string) reader {
+	if format == BinaryFormat {
+		return binaryReader(bufio.NewReader(r))
+	}
+
+	return ndjsonReader(bufio.NewReader(r))
+}
+
+// ndjsonReader - Will read newline delimited json records (see events.JSONCodec)
+// skipping blank lines
+func ndjsonReader(r *bufio.Reader) reader {
+	line := 0
+	codec := events.JSONCodec{}
+
+	return func() (record, error) {
+		for {
+			data, err := r.ReadBytes('\n')
+			line++
+
+			if len(bytes.TrimSpace(data)) == 0 {
+				if err != nil {
+					return record{}, err
+				}
+
+				continue
+			}
+
+			event, err := codec.Decode(data)
+
+			if err != nil {
+				return record{}, fmt.Errorf("Could not decode ndjson record (line: %d) due to (err: %s)", line, err)
+			}
+
+			return newRecord(event), nil
+		}
+	}
+}
+
+// binaryReader - Will read length prefixed records (see events.BinaryCodec)
+func binaryReader(r *bufio.Reader) reader {
+	codec := events.BinaryCodec{}
+
+	return func() (record, error) {
+		event, err := codec.Read(r)
+
+		if err != nil {
+			return record{}, err
+		}
+
+		return newRecord(event), nil
+	}
+}
+
+// newRecord - Will take record out of decoded event. Event is released, replay
+// builds fresh one once record is due.
+func newRecord(e events.Event) record {
+	rec := record{topic: e.Topic(), payload: e.Payload(), timestamp: e.ReceivedAt, sequence: e.Sequence, source: e.Source}
+	e.Release()
+
+	return rec
+}
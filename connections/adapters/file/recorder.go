@@ -0,0 +1,198 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package file ...
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/powerunit-io/platform/events"
+)
+
+// sequence - Last ordering key handed out to the record. Shared by all
+// recorders of the process, so records of its concurrent captures interleave in
+// the order they were written. It's not comparable across processes, replay
+// orders records by timestamp and source first (see SequenceOrder).
+var sequence = uint64(time.Now().UnixNano())
+
+// Recorder - Will write messages into ndjson capture (see events.JSONCodec)
+// replayable by the file adapter. Each record carries monotonic sequence and
+// source name, so replay can restore the order of messages captured from
+// concurrent sources. Capture is rotated once it grows over max size or gets
+// older than max age; rotated files are renamed to `<path>.<unix nanoseconds>`.
+type Recorder struct {
+	path    string
+	source  string
+	maxSize int64
+	maxAge  time.Duration
+	codec   events.JSONCodec
+
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	lock     sync.Mutex
+}
+
+// NewRecorder - Will open (append to) capture at path. Zero max size or max age
+// disables rotation by that criteria.
+func NewRecorder(path string, maxSize int64, maxAge time.Duration) (*Recorder, error) {
+	r := &Recorder{path: path, maxSize: maxSize, maxAge: maxAge}
+
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// SetSource - Will set source name (e.g. connection name) stored with records
+// not carrying one of their own
+func (r *Recorder) SetSource(source string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.source = source
+}
+
+// Record - Will append single message to the capture. Payloads which are valid
+// json are stored as json values, all others as json strings (text or base64,
+// see events.JSONCodec). Metadata is informative only and is not replayed.
+func (r *Recorder) Record(topic string, payload []byte, metadata map[string]interface{}) error {
+	event := events.Event{Message: &message{topic: topic, payload: payload}, ReceivedAt: time.Now()}
+	return r.write(event, metadata)
+}
+
+// RecordEvent - Will append received event to the capture keeping its receive
+// time, qos, retained flag and source
+func (r *Recorder) RecordEvent(e events.Event) error {
+	return r.write(e, nil)
+}
+
+// write - Will stamp event with next sequence and append it as single line,
+// rotating capture if due. Sequence is taken under the lock so records are
+// written in sequence order.
+func (r *Recorder) write(e events.Event, metadata map[string]interface{}) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.file == nil {
+		return fmt.Errorf("Could not record message as (file: %s) is closed", r.path)
+	}
+
+	if e.Source == "" {
+		e.Source = r.source
+	}
+
+	e.Sequence = atomic.AddUint64(&sequence, 1)
+	data, err := r.codec.Encode(e)
+
+	if err != nil {
+		return err
+	}
+
+	if len(metadata) > 0 {
+		if data, err = withMetadata(data, metadata); err != nil {
+			return fmt.Errorf("Could not encode record metadata for (topic: %s) due to (err: %s)", e.Topic(), err)
+		}
+	}
+
+	if r.rotationDue(len(data) + 1) {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := r.file.Write(append(data, '\n'))
+	r.size += int64(n)
+
+	return err
+}
+
+// withMetadata - Will add metadata into json encoded record
+func withMetadata(data []byte, metadata map[string]interface{}) ([]byte, error) {
+	rec := map[string]json.RawMessage{}
+
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(metadata)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rec["metadata"] = json.RawMessage(encoded)
+
+	return json.Marshal(rec)
+}
+
+// Close - Will close the capture
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+
+	err := r.file.Close()
+	r.file = nil
+
+	return err
+}
+
+// rotationDue - Will return whenever writing next record of length would exceed
+// limits. Empty capture is never rotated.
+func (r *Recorder) rotationDue(length int) bool {
+	if r.size == 0 {
+		return false
+	}
+
+	if r.maxSize > 0 && r.size+int64(length) > r.maxSize {
+		return true
+	}
+
+	return r.maxAge > 0 && time.Since(r.openedAt) > r.maxAge
+}
+
+// rotate - Will move current capture aside and open fresh one
+func (r *Recorder) rotate() error {
+	r.file.Close()
+	r.file = nil
+
+	rotated := fmt.Sprintf("%s.%d", r.path, time.Now().UnixNano())
+
+	if err := os.Rename(r.path, rotated); err != nil {
+		return fmt.Errorf("Could not rotate (file: %s) into (file: %s) due to (err: %s)", r.path, rotated, err)
+	}
+
+	return r.open()
+}
+
+// open - Will open capture for appending
+func (r *Recorder) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+
+	if err != nil {
+		return fmt.Errorf("Could not open (file: %s) for recording due to (err: %s)", r.path, err)
+	}
+
+	info, err := file.Stat()
+
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("Could not stat (file: %s) for recording due to (err: %s)", r.path, err)
+	}
+
+	r.file, r.size, r.openedAt = file, info.Size(), time.Now()
+
+	return nil
+}
@@ -0,0 +1,41 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package file ...
+package file
+
+import "time"
+
+const (
+	// Kind - Kind of the service reported to the managers
+	Kind = "file"
+
+	// NDJSONFormat - One events.JSONCodec record per line
+	NDJSONFormat = "ndjson"
+
+	// BinaryFormat - Stream of events.BinaryCodec length prefixed records
+	BinaryFormat = "binary"
+
+	// SequenceOrder - Records are replayed by recorded timestamp, source and
+	// sequence (see Recorder)
+	SequenceOrder = "sequence"
+
+	// TimestampOrder - Records are replayed by recorded timestamp
+	TimestampOrder = "timestamp"
+
+	// FileOrder - Records are streamed in order they are stored, file by file
+	FileOrder = "file"
+)
+
+var (
+	// AvailableFormats -
+	AvailableFormats = []string{NDJSONFormat, BinaryFormat}
+
+	// AvailableOrders -
+	AvailableOrders = []string{SequenceOrder, TimestampOrder, FileOrder}
+
+	// MaxTimingGap - Longest pause honored between two replayed records. Longer
+	// gaps (e.g. capture was paused) are shortened to it.
+	MaxTimingGap = 10 * time.Second
+)
@@ -0,0 +1,89 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"fmt"
+
+	"github.com/powerunit-io/platform/utils"
+)
+
+// allowed - Will return whenever messages on the topic (without topicPrefix)
+// may be processed according to `allowTopics` and `denyTopics` filters. Deny
+// takes precedence; without allow filters every topic not denied is allowed.
+func (c *Connection) allowed(topic string) bool {
+	for _, filter := range c.GetDenyTopics() {
+		if _, ok := utils.MatchTopic(filter, topic); ok {
+			return false
+		}
+	}
+
+	allow := c.GetAllowTopics()
+
+	if len(allow) == 0 {
+		return true
+	}
+
+	for _, filter := range allow {
+		if _, ok := utils.MatchTopic(filter, topic); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetAllowTopics - will return topic filters (without topicPrefix) messages
+// have to match in order to be processed
+func (c *Connection) GetAllowTopics() []string {
+	return c.getTopicFilters("allowTopics")
+}
+
+// GetDenyTopics - will return topic filters (without topicPrefix) of messages
+// which are always dropped
+func (c *Connection) GetDenyTopics() []string {
+	return c.getTopicFilters("denyTopics")
+}
+
+// getTopicFilters -
+func (c *Connection) getTopicFilters(key string) []string {
+	filters := []string{}
+	list, _ := c.connection()[key].([]interface{})
+
+	for _, filter := range list {
+		if value, ok := filter.(string); ok && value != "" {
+			filters = append(filters, value)
+		}
+	}
+
+	return filters
+}
+
+// validateTopicFilters - will ensure that allow and deny lists are lists of
+// topic filters
+func (c *Connection) validateTopicFilters(data map[string]interface{}) error {
+	for _, key := range []string{"allowTopics", "denyTopics"} {
+		value, ok := data[key]
+
+		if !ok {
+			continue
+		}
+
+		list, ok := value.([]interface{})
+
+		if !ok {
+			return fmt.Errorf("Could not validate mqtt worker as connection %s is not a list. (%s: %v)", key, key, value)
+		}
+
+		for _, filter := range list {
+			if topic, ok := filter.(string); !ok || topic == "" {
+				return fmt.Errorf("Could not validate mqtt worker as connection %s contain invalid (topic: %v)", key, filter)
+			}
+		}
+	}
+
+	return nil
+}
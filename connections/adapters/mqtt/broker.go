@@ -0,0 +1,166 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+
+	"github.com/powerunit-io/platform/utils"
+)
+
+// BrokerAddress - Single broker of the `address` list together with its weight
+type BrokerAddress struct {
+	Address string
+	Weight  int
+}
+
+// GetBrokerAddresses - will return brokers defined by `address` config. Address
+// is either single "host:port" string or list of brokers, where each entry is
+// "host:port" string (weight DefaultBrokerWeight) or {"address", "weight"} map.
+func (c *Connection) GetBrokerAddresses() []BrokerAddress {
+	addresses := []BrokerAddress{}
+
+	switch address := c.connection()["address"].(type) {
+	case string:
+		addresses = append(addresses, BrokerAddress{Address: address, Weight: DefaultBrokerWeight})
+	case []interface{}:
+		for _, entry := range address {
+			if broker, err := parseBrokerEntry(entry); err == nil {
+				addresses = append(addresses, broker)
+			}
+		}
+	}
+
+	return addresses
+}
+
+// pickBroker - Will select broker address for next connect attempt, weighted
+// randomly out of configured ones, and remember it as current (see
+// GetBrokerAddr)
+func (c *Connection) pickBroker() string {
+	addresses := c.GetBrokerAddresses()
+	address := ""
+
+	total := 0
+	for _, broker := range addresses {
+		total += broker.Weight
+	}
+
+	if total > 0 {
+		pick := rand.Intn(total)
+
+		for _, broker := range addresses {
+			if pick -= broker.Weight; pick < 0 {
+				address = broker.Address
+				break
+			}
+		}
+	}
+
+	c.brokerLock.Lock()
+	c.broker = address
+	c.brokerLock.Unlock()
+
+	return address
+}
+
+// brokerURI - will return full broker uri string (protocol://addr:port?params)
+func (c *Connection) brokerURI(address string) string {
+	network, _ := utils.AsString(c.connection()["network"])
+
+	if host, port, err := utils.ParseBrokerAddress(address, c.GetDefaultBrokerPort()); err == nil {
+		address = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+
+	return fmt.Sprintf("%s://%s?timeout=10s", network, address)
+}
+
+// ClientIdentity - will return client id together with normalized (host:port)
+// addresses of configured brokers, see managers.ClientIdentified
+func (c *Connection) ClientIdentity() (string, []string) {
+	brokers := []string{}
+
+	for _, broker := range c.GetBrokerAddresses() {
+		address := broker.Address
+
+		if host, port, err := utils.ParseBrokerAddress(address, c.GetDefaultBrokerPort()); err == nil {
+			address = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+
+		brokers = append(brokers, address)
+	}
+
+	return c.GetBrokerClientID(), brokers
+}
+
+// ValidateBrokerAddresses - will ensure that `address` is valid broker address
+// or non empty list of (optionally weighted) ones
+func (c *Connection) ValidateBrokerAddresses(data map[string]interface{}) error {
+	var entries []interface{}
+
+	switch address := data["address"].(type) {
+	case string:
+		entries = []interface{}{address}
+	case []interface{}:
+		entries = address
+	default:
+		return fmt.Errorf(
+			"Could not validate mqtt worker as connection address is not set. (connection_data: %q)",
+			c.Redact(data),
+		)
+	}
+
+	if len(entries) == 0 {
+		return fmt.Errorf("Could not validate mqtt worker as connection address list is empty")
+	}
+
+	for _, entry := range entries {
+		broker, err := parseBrokerEntry(entry)
+
+		if err != nil {
+			return fmt.Errorf("Could not validate mqtt worker as connection address is not valid. (address: %v) - (err: %s)", entry, err)
+		}
+
+		if _, _, err := utils.ParseBrokerAddress(broker.Address, c.GetDefaultBrokerPort()); err != nil {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection address is not valid. (address: %s) - (err: %s)",
+				broker.Address, err,
+			)
+		}
+	}
+
+	return nil
+}
+
+// parseBrokerEntry - Will parse single entry of the address list
+func parseBrokerEntry(entry interface{}) (BrokerAddress, error) {
+	if address, ok := entry.(string); ok {
+		return BrokerAddress{Address: address, Weight: DefaultBrokerWeight}, nil
+	}
+
+	data, ok := utils.AsStringMap(entry)
+
+	if !ok {
+		return BrokerAddress{}, fmt.Errorf("Broker entry MUST be string or map of address and weight")
+	}
+
+	broker := BrokerAddress{Weight: DefaultBrokerWeight}
+
+	if broker.Address, ok = data["address"].(string); !ok {
+		return BrokerAddress{}, fmt.Errorf("Broker entry address is not set")
+	}
+
+	if weight, ok := data["weight"]; ok {
+		if broker.Weight, ok = utils.AsInt(weight); !ok || broker.Weight < 1 {
+			return BrokerAddress{}, fmt.Errorf("Broker entry (weight: %v) MUST be positive number", weight)
+		}
+	}
+
+	return broker, nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/powerunit-io/platform/utils"
+)
+
+// Clone - Will return fresh, not started, connection with deep copy of the
+// configuration, e.g. for consuming high volume topic by several parallel
+// subscribers. Clone is named `<name>-<n>` and its `clientId` gets the same
+// `-<n>` suffix so clones never take over each other's broker session. Context
+// values (see WithValue) are shared; handlers, transforms and other runtime
+// registrations are not copied.
+func (c *Connection) Clone() (Adapter, error) {
+	index := atomic.AddInt32(&c.clones, 1)
+	suffix := fmt.Sprintf("-%d", index)
+
+	conf := utils.CopyMap(c.Config.Config)
+
+	if connection, ok := utils.AsStringMap(conf["connection"]); ok {
+		if clientID, ok := connection["clientId"].(string); ok && clientID != "" {
+			connection["clientId"] = clientID + suffix
+		}
+	}
+
+	c.Info("Cloning mqtt (worker: %s) into (worker: %s) ...", c.Name(), c.Name()+suffix)
+
+	clone, err := NewAdapter(c.Name()+suffix, conf, c.Logger)
+
+	if err != nil {
+		return nil, err
+	}
+
+	clone.(*Connection).values = c.connValues()
+
+	return clone, nil
+}
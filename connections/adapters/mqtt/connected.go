@@ -0,0 +1,35 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import "github.com/powerunit-io/platform/events"
+
+// emitConnected - Will push events.ConnectedEvent into the event stream in case
+// that `connectedEvent` is enabled, once subscriptions are in place. Event data
+// holds `broker` address and `reconnect` flag. Event is pushed in background so
+// full buffer does not hold back the connect loop.
+func (c *Connection) emitConnected(reconnect bool) {
+	if !c.GetConnectedEvent() {
+		return
+	}
+
+	event := events.NewSystemEvent(events.ConnectedEvent, map[string]interface{}{
+		"broker":    c.GetBrokerAddr(),
+		"reconnect": reconnect,
+	})
+	event.ConnValues = c.connValues()
+
+	c.Debug("Emitting (event: %s) for mqtt (worker: %s) - (reconnect: %t)", events.ConnectedEvent, c.Name(), reconnect)
+
+	go c.push(event)
+}
+
+// GetConnectedEvent - will return whenever events.ConnectedEvent is pushed into
+// the event stream on each successful connect. Defaults to false.
+func (c *Connection) GetConnectedEvent() bool {
+	enabled, _ := c.connection()["connectedEvent"].(bool)
+	return enabled
+}
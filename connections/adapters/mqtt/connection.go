@@ -6,13 +6,23 @@
 package mqtt
 
 import (
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/powerunit-io/platform/config"
+	"github.com/powerunit-io/platform/connections"
+	"github.com/powerunit-io/platform/connections/adapters/file"
 	"github.com/powerunit-io/platform/events"
 	"github.com/powerunit-io/platform/logging"
+	"github.com/powerunit-io/platform/managers"
 	"github.com/powerunit-io/platform/utils"
 
 	MQTT "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
@@ -22,62 +32,288 @@ import (
 type Connection struct {
 	*logging.Logger
 	*config.Config
+	managers.PhaseTracker
+
+	conn        *MQTT.Client
+	connectedAt time.Time
+	connLock    sync.Mutex
 
-	conn   *MQTT.Client
 	events chan events.Event
+	done   chan bool
+
+	transforms []events.Transform
+	validator  events.Validator
+	decryptor  events.Decryptor
+	encryptor  events.Encryptor
+	types      events.TypeRegistry
+	taps       []func(topic string, payload []byte)
+	slots      chan bool
+
+	lastMessageAt   time.Time
+	lastMessageLock sync.Mutex
+
+	pending     map[MQTT.Token]bool
+	pendingLock sync.Mutex
+
+	outbox     []queuedPublish
+	onExpired  func(topic string)
+	outboxLock sync.Mutex
+
+	deadLetters     deadLetters
+	deadLettersLock sync.Mutex
+
+	granted     map[string]byte
+	requested   map[string]byte
+	topicStats  map[string]*topicStat
+	grantedLock sync.Mutex
+
+	subscriptions     []string
+	subscriptionsLock sync.Mutex
+
+	responses     map[string]*responseRoute
+	responsesLock sync.Mutex
+
+	consumer     string
+	sink         chan<- events.Event
+	pushing      int
+	consumerLock sync.Mutex
+
+	resumed   chan bool
+	pauseLock sync.Mutex
+
+	delayed     map[*delayedEvent]bool
+	delayedLock sync.Mutex
+
+	elector    Elector
+	leaderOnce sync.Once
+	isLeading  bool
+	leaderLock sync.Mutex
+
+	recorder     *file.Recorder
+	recorderLock sync.Mutex
+
+	storm     reconnectStorm
+	stormLock sync.Mutex
+
+	slow     slowConsumer
+	slowLock sync.Mutex
+
+	snapshot     initialSnapshot
+	snapshotLock sync.Mutex
+
+	stop     chan bool
+	stopOnce sync.Once
+	routines sync.WaitGroup
+
+	disconnectReason     error
+	disconnectReasonLock sync.Mutex
+
+	stopReason     managers.StopReason
+	stopReasonLock sync.Mutex
+
+	broker     string
+	resolved   map[string]string
+	resolver   Resolver
+	brokerLock sync.Mutex
+
+	clones int32
+
+	values     events.ConnValues
+	valuesLock sync.Mutex
 }
 
-// Start -
+// Start - Will connect to the broker and subscribe. Unless `validateOnStart`
+// is disabled, configuration is validated first and validation error is
+// returned without attempting to connect.
 func (c *Connection) Start(done chan bool) error {
-	opts := MQTT.NewClientOptions().AddBroker(c.GetBrokerAddr())
+	if c.GetValidateOnStart() {
+		if err := c.Validate(); err != nil {
+			c.Error("Refusing to start mqtt (worker: %s) due to invalid configuration (err: %s)", c.Name(), err)
+			return err
+		}
+	}
+
+	opts, tlsConfig, err := c.clientOptions()
+
+	if err != nil {
+		return err
+	}
+
+	c.SetupMetrics()
+	c.SetupBrokerLogging()
+	c.done = done
+
+	if c.elector != nil {
+		c.leaderOnce.Do(func() {
+			c.routines.Add(1)
+			go c.watchLeadership()
+		})
+	}
+
+	return c.connect(opts, tlsConfig)
+}
+
+// clientOptions - Will build paho client options together with tls config
+// shared by all brokers
+func (c *Connection) clientOptions() (*MQTT.ClientOptions, *tls.Config, error) {
+	opts := MQTT.NewClientOptions()
 	opts.SetClientID(c.GetBrokerClientID())
 	opts.SetDefaultPublishHandler(c.BrokerHandler)
-	opts.SetAutoReconnect(true)
+	opts.SetConnectionLostHandler(c.ConnectionLostHandler)
+	// Reconnects are driven by the connect loop, so the reason of each lost
+	// connection is classified before connecting again
+	opts.SetAutoReconnect(false)
+	opts.SetStore(c.GetBrokerStore())
+	opts.SetOrderMatters(c.GetOrderMatters())
 
 	username, password := c.GetBrokerCredentials()
 	opts.SetUsername(username)
 	opts.SetPassword(password)
 
-	concurrency := utils.GetConcurrencyCount("PU_GO_MAX_CONCURRENCY")
-	c.events = make(chan events.Event, concurrency)
+	tlsConfig, err := c.GetTLSConfig()
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return opts, tlsConfig, nil
+}
+
+// connect - Will run connect loop in background, connecting again whenever
+// broker connection is lost. Blocks until first connection is established,
+// attempts are exhausted or initial connection times out.
+func (c *Connection) connect(opts *MQTT.ClientOptions, tlsConfig *tls.Config) error {
+	c.SetPhase(managers.PhaseConnecting)
 
-	errors := make(chan error)
+	errors := make(chan error, 1)
 	connected := make(chan bool)
 
+	// Loop below reaches the ready signal on each reconnect, while connected
+	// channel may be closed only once
+	var ready sync.Once
+
+	c.routines.Add(1)
+
 	go func() {
+		defer c.routines.Done()
+
+		attempts := 0
+
 		for {
+
+			// Broker is picked (and resolved) again on each attempt so failover
+			// spreads by weight and follows dns changes
+			server, serverTLS := c.brokerServer(c.pickBroker(), tlsConfig)
+			opts.Servers = nil
+			opts.AddBroker(server)
+
+			// Reset on each attempt so server name pinned for another broker
+			// never leaks into this one
+			if serverTLS == nil {
+				serverTLS = &tls.Config{}
+			}
+			opts.SetTLSConfig(serverTLS)
+
 			c.Info("Starting MQTT (connection: %s) on (addr: %s)...", c.Name(), c.GetBrokerAddr())
 
-			reload := make(chan bool)
-			c.conn = MQTT.NewClient(opts)
+			reload := make(chan bool, 1)
+			conn := MQTT.NewClient(opts)
+			c.setClient(conn)
+
+			if token := conn.Connect(); token.Wait() && token.Error() != nil {
+				attempts++
+				maxAttempts := c.GetMaxConnectAttempts()
+
+				if c.Phase() == managers.PhaseReconnecting {
+					maxAttempts = c.GetMaxReconnectAttempts()
+				}
+				c.setDisconnectReason(token.Error())
+				c.Disconnected()
+				c.countReconnect()
+
+				c.Error(
+					"Failed to establish connection with mqtt server for (worker: %s) - (attempt: %d/%d) due to (error: %s)",
+					c.Name(), attempts, maxAttempts, token.Error(),
+				)
+
+				if isNotAuthorized(token.Error()) {
+					c.Error("Broker refused mqtt (worker: %s) as not authorized. Will not attempt to reconnect ...", c.Name())
+					c.setStopReason(managers.StopReason{Kind: managers.StopFatal, Err: ErrNotAuthorized})
+					errors <- ErrNotAuthorized
+					return
+				}
+
+				if maxAttempts > 0 && attempts >= maxAttempts {
+					c.Error(
+						"Giving up connecting mqtt (worker: %s) after (attempts: %d). Connection is failed until Reconnect ...",
+						c.Name(), attempts,
+					)
+					c.SetPhase(managers.PhaseFailed)
+					c.setStopReason(managers.StopReason{Kind: managers.StopFatal, Err: ErrBrokerUnreachable})
+					errors <- ErrBrokerUnreachable
+					return
+				}
+
+				time.Sleep(c.reconnectDelay(token.Error()))
+
+				if c.stopping() {
+					return
+				}
 
-			if token := c.conn.Connect(); token.Wait() && token.Error() != nil {
-				errors <- fmt.Errorf("Failed to establish connection with mqtt server (error: %s)", token.Error())
 				continue
 			}
 
-			if !c.conn.IsConnected() {
+			attempts = 0
+
+			if !conn.IsConnected() {
 				continue
 			}
 
-			c.Subscribe(c.GetBrokerTopicName(), MaxTopicSubscribeAttempts)
+			c.connLock.Lock()
+			reconnect := !c.connectedAt.IsZero()
+			c.connectedAt = time.Now()
+			c.connLock.Unlock()
+
+			c.SetPhase(managers.PhaseConnected)
+			c.startSnapshot()
+
+			if err := c.subscribeAll(); err == ErrSubscriptionRejected && c.GetStrictSubscribe() {
+				c.setStopReason(managers.StopReason{Kind: managers.StopFatal, Err: err})
+				errors <- err
+				return
+			}
+
+			c.emitConnected(reconnect)
+			c.flushOutbox()
 
 			// Notify rest of the app that we're ready ...
-			close(connected)
+			ready.Do(func() { close(connected) })
+
+			c.routines.Add(1)
 
 			go func() {
-				cct := time.Tick(2 * time.Second)
+				defer c.routines.Done()
+
+				cct := time.NewTicker(2 * time.Second)
+				defer cct.Stop()
 
 				for {
 					select {
-					case <-cct:
-						if !c.conn.IsConnected() {
+					case <-cct.C:
+						if c.stopping() {
+							return
+						}
+
+						if !conn.IsConnected() {
+							c.Disconnected()
 							reload <- true
 							return
 						}
-					case <-done:
+					case <-c.done:
 						c.Warning("Received stop signal for mqtt (worker: %s). Will not attempt to restart worker ...", c.Name())
 						return
+					case <-c.stop:
+						return
 					}
 				}
 			}()
@@ -86,9 +322,26 @@ func (c *Connection) Start(done chan bool) error {
 			for {
 				select {
 				case <-reload:
-					c.Warning("Mqtt (worker: %s) seems not to be connected. Restarting loop in 2 seconds ...", c.Name())
-					time.Sleep(2 * time.Second)
+					reason := c.LastDisconnectReason()
+
+					if isNotAuthorized(reason) {
+						c.Error("Broker disconnected mqtt (worker: %s) as not authorized. Will not attempt to reconnect ...", c.Name())
+						c.SetPhase(managers.PhaseFailed)
+						c.setStopReason(managers.StopReason{Kind: managers.StopFatal, Err: ErrNotAuthorized})
+						return
+					}
+
+					delay := c.reconnectDelay(reason)
+					c.Warning(
+						"Mqtt (worker: %s) seems not to be connected. Restarting loop in (interval: %s) ...",
+						c.Name(), delay,
+					)
+					time.Sleep(delay)
 					break reloadloop
+				case <-c.done:
+					return
+				case <-c.stop:
+					return
 				}
 			}
 
@@ -103,74 +356,172 @@ func (c *Connection) Start(done chan bool) error {
 		)
 		break
 
-	// @TODO - Figure out how to handle multiple errors ...
 	case err := <-errors:
 		return err
-	case <-time.After(time.Duration(InitialConnectionTimeout) * time.Second):
-		errors <- fmt.Errorf(
-			"Could not establish mqtt connection for (worker: %s) on (addr: %s) due to initial connection (timeout: %ds)",
-			c.Name(), c.GetBrokerAddr(), InitialConnectionTimeout,
+	case <-time.After(c.GetConnectTimeout()):
+		return fmt.Errorf(
+			"Could not establish mqtt connection for (worker: %s) on (addr: %s) due to initial connection (timeout: %s)",
+			c.Name(), c.GetBrokerAddr(), c.GetConnectTimeout(),
 		)
-		break
 	}
 
 	return nil
 }
 
-// DrainEvents - Will return event chan back for future processing by workers
+// DrainEvents - Will return event chan back for future processing by workers.
+// Connection is consumed either by channel, by OnEvent callback or by PipeTo,
+// so nil chan is returned in case that callback or pipe is already registered.
 func (c *Connection) DrainEvents() chan events.Event {
+	if err := c.claim(ChannelConsumer); err != nil {
+		return nil
+	}
+
 	return c.events
 }
 
-// Subscribe -
-func (c *Connection) Subscribe(topic string, maxRetryAttempts int) error {
-	var err error
+// Stream - Will return composable pipeline (filter, map, batch, ...) over the
+// events chan, see events.Stream. It claims events the same way DrainEvents
+// does, so nil is returned in case that callback or pipe is already registered.
+func (c *Connection) Stream() *events.Stream {
+	ch := c.DrainEvents()
 
-	for i := 0; i <= maxRetryAttempts; i++ {
-		c.Info(
-			"About to attempt subscribe to mqtt (topic: %s) for (worker: %s) -> (retry_attempt: %d)",
-			topic, c.Name(), i,
-		)
+	if ch == nil {
+		return nil
+	}
+
+	return events.NewStream(ch)
+}
+
+// WaitForMessage - Will return next event or error once timeout expires.
+// Intended for tests.
+func (c *Connection) WaitForMessage(timeout time.Duration) (events.Event, error) {
+	return events.WaitFor(c.DrainEvents(), timeout)
+}
+
+// WaitForMessageMatching - Will return next event satisfying the predicate or
+// error once timeout expires. Events not matching are consumed and released.
+// Intended for tests.
+func (c *Connection) WaitForMessageMatching(pred func(events.Event) bool, timeout time.Duration) (events.Event, error) {
+	return events.WaitForMatching(c.DrainEvents(), pred, timeout)
+}
+
+// Validate -
+func (c *Connection) Validate() error {
+	c.Info("Validating mqtt configuration for (worker: %q)", c.Name())
+
+	if err := c.ValidateConnection(); err != nil {
+		return err
+	}
+
+	return c.ValidateTopic()
+}
+
+// ValidateTopic - Will validate worker specific (topic) part of the connection
+// configuration. Connection block itself is validated by ValidateConnection.
+func (c *Connection) ValidateTopic() error {
+	data := c.connection()
 
-		if token := c.conn.Subscribe(topic, 0, nil); token.Wait() && token.Error() != nil {
-			c.Error("Could not subscribe to (topic: %s) for (worker: %s) due to (err: %s). Retrying ...")
-			err = token.Error()
-			continue
+	if err := c.validateTopicFilters(data); err != nil {
+		return err
+	}
+
+	if _, ok := data["units"]; ok {
+		rules, err := c.GetUnitRules()
+
+		if err == nil {
+			_, err = events.NormalizeUnits(rules)
 		}
 
-		c.Info("Successfully subscribed (worker: %s) on (topic: %s)!",
-			c.Name(), c.GetBrokerTopicName(),
-		)
+		if err != nil {
+			return fmt.Errorf("Could not validate mqtt worker as connection units are not valid due to (err: %s)", err)
+		}
+	}
 
-		err = nil
-		break
+	if deferSubscribe, ok := data["deferSubscribe"]; ok {
+		if _, ok := deferSubscribe.(bool); !ok {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection deferSubscribe is not boolean. (defer_subscribe: %v)",
+				deferSubscribe,
+			)
+		}
 	}
 
-	return err
+	if topics, ok := data["topics"]; ok {
+		list, ok := topics.([]interface{})
+
+		if !ok {
+			return fmt.Errorf("Could not validate mqtt worker as connection topics are not a list. (topics: %v)", topics)
+		}
+
+		for _, topic := range list {
+			if value, ok := topic.(string); !ok || value == "" {
+				return fmt.Errorf("Could not validate mqtt worker as connection topics contain invalid (topic: %v)", topic)
+			}
+		}
+
+		if len(list) > MaxTopicsWarning {
+			c.Warning(
+				"Mqtt (worker: %s) is configured with (topics: %d). They are subscribed in batches of (max_topics_per_subscribe: %d)",
+				c.Name(), len(list), c.GetMaxTopicsPerSubscribe(),
+			)
+		}
+	}
+
+	if size, ok := data["maxTopicsPerSubscribe"]; ok {
+		if value, ok := utils.AsInt(size); !ok || value < 1 {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection maxTopicsPerSubscribe is not valid. It MUST be positive number. (max_topics_per_subscribe: %v)",
+				size,
+			)
+		}
+	}
+
+	if topic, ok := data["topic"]; ok || (!c.GetDeferSubscribe() && len(c.GetTopics()) == 0) {
+		if _, ok := topic.(string); !ok {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection topic is not set. (connection_data: %q)",
+				c.Redact(data),
+			)
+		}
+	}
+
+	if topic, ok := c.GetDeadLetterTopic(); ok {
+		if filter, loops := c.matchingSubscription(topic); loops {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection deadLetterTopic is matched by its own (subscription: %s). (dead_letter_topic: %s)",
+				filter, topic,
+			)
+		}
+	}
+
+	return nil
 }
 
-// BrokerHandler -
-func (c *Connection) BrokerHandler(client *MQTT.Client, msg MQTT.Message) {
-	c.Info(
-		"Received new mqtt (worker: %s) - (message: %s) for (topic: %s). Building event now ...",
-		c.Name(), msg.Payload(), msg.Topic(),
-	)
+// ConnectionFingerprint - Will return identity of broker settings (connection
+// block without topic specific keys). Workers sharing the same broker settings
+// have the same fingerprint, so managers validate the block only once. Empty
+// fingerprint (block can't be serialized) disables deduplication.
+func (c *Connection) ConnectionFingerprint() string {
+	shared := map[string]interface{}{}
+
+	for key, value := range c.connection() {
+		if !utils.StringInSlice(key, TopicConfigKeys) {
+			shared[key] = value
+		}
+	}
 
-	event, err := events.NewEvent(msg)
+	fingerprint, err := json.Marshal(shared)
 
 	if err != nil {
-		c.Error("Could not handle received event due to (err: %s)", err)
-		return
+		return ""
 	}
 
-	c.Info("Event successfully created (data: %v)", event)
-	c.events <- event
+	return Kind + ":" + string(fingerprint)
 }
 
-// Validate -
-func (c *Connection) Validate() error {
-	c.Info("Validating mqtt configuration for (worker: %q)", c.Name())
-
+// ValidateConnection - Will validate broker settings (everything but topic
+// specific keys, see ValidateTopic)
+func (c *Connection) ValidateConnection() error {
 	if c.Config.Get("connection") == nil {
 		return fmt.Errorf(
 			"Could not validate mqtt worker as connection interface is missing (entry: %s)",
@@ -178,12 +529,25 @@ func (c *Connection) Validate() error {
 		)
 	}
 
-	data := c.Config.Get("connection").(map[string]interface{})
+	data, ok := utils.AsStringMap(c.Config.Get("connection"))
+
+	if !ok {
+		return fmt.Errorf(
+			"Could not validate mqtt worker as connection interface is not a map (entry: %T)",
+			c.Config.Get("connection"),
+		)
+	}
+
+	if tags := c.Config.Get("tags"); tags != nil {
+		if _, ok := utils.AsStringMap(tags); !ok {
+			return fmt.Errorf("Could not validate mqtt worker as tags are not a map (tags: %v)", tags)
+		}
+	}
 
 	if _, ok := data["network"].(string); !ok {
 		return fmt.Errorf(
 			"Could not validate mqtt worker as connection network is not set. (connection_data: %q)",
-			data,
+			c.Redact(data),
 		)
 	}
 
@@ -194,42 +558,30 @@ func (c *Connection) Validate() error {
 		)
 	}
 
-	if _, ok := data["address"].(string); !ok {
-		return fmt.Errorf(
-			"Could not validate mqtt worker as connection address is not set. (connection_data: %q)",
-			data,
-		)
-	}
-
-	address := data["address"].(string)
-
-	if len(address) < 5 || !strings.Contains(address, ":") {
-		return fmt.Errorf(
-			"Could not validate mqtt worker as connection address is not valid. (address: %s)",
-			address,
-		)
+	if err := c.ValidateBrokerAddresses(data); err != nil {
+		return err
 	}
 
 	if _, ok := data["username"].(string); !ok {
 		return fmt.Errorf(
 			"Could not validate mqtt worker as connection username is not set. Username can be empty but it MUST be set. (connection_data: %q)",
-			data,
+			c.Redact(data),
 		)
 	}
 
 	if _, ok := data["password"].(string); !ok {
 		return fmt.Errorf(
 			"Could not validate mqtt worker as connection password is not set. Password can be empty but it MUST be set. (connection_data: %q)",
-			data,
+			c.Redact(data),
 		)
 	}
 
-	clientID := data["clientId"].(string)
+	clientID, ok := data["clientId"].(string)
 
-	if _, ok := data["clientId"].(string); !ok {
+	if !ok {
 		return fmt.Errorf(
-			"Could not validate mqtt worker as connection clientId is not set. (client_id: %s)",
-			clientID,
+			"Could not validate mqtt worker as connection clientId is not set. (client_id: %v)",
+			data["clientId"],
 		)
 	}
 
@@ -240,43 +592,874 @@ func (c *Connection) Validate() error {
 		)
 	}
 
-	if _, ok := data["topic"].(string); !ok {
+	if format, ok := data["payloadFormat"]; ok {
+		if _, ok := format.(string); !ok || !utils.StringInSlice(format.(string), AvailablePayloadFormats) {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection payloadFormat is not valid. (payload_format: %v) - (available_payload_formats: %v)",
+				format, AvailablePayloadFormats,
+			)
+		}
+	}
+
+	if encoding, ok := data["payloadEncoding"]; ok {
+		if value, ok := encoding.(string); !ok || !utils.StringInSlice(value, AvailablePayloadEncodings) {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection payloadEncoding is not valid. (payload_encoding: %v) - (available_payload_encodings: %v)",
+				encoding, AvailablePayloadEncodings,
+			)
+		}
+	}
+
+	if attempts, ok := data["maxConnectAttempts"]; ok {
+		if max, ok := utils.AsInt(attempts); !ok || max < 0 {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection maxConnectAttempts is not valid. It MUST be 0 (infinite) or positive number. (max_connect_attempts: %v)",
+				attempts,
+			)
+		}
+	}
+
+	if attempts, ok := data["maxReconnectAttempts"]; ok {
+		if max, ok := utils.AsInt(attempts); !ok || max < 0 {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection maxReconnectAttempts is not valid. It MUST be 0 (infinite) or positive number. (max_reconnect_attempts: %v)",
+				attempts,
+			)
+		}
+	}
+
+	if qos, ok := data["qos"]; ok {
+		if level, ok := utils.AsInt(qos); !ok || level < 0 || level > 2 {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection qos is not valid. It MUST be 0, 1 or 2. (qos: %v)",
+				qos,
+			)
+		}
+	}
+
+	if orderMatters, ok := data["orderMatters"]; ok {
+		if _, ok := orderMatters.(bool); !ok {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection orderMatters is not boolean. (order_matters: %v)",
+				orderMatters,
+			)
+		}
+	}
+
+	if label, ok := data["metricsLabel"]; ok {
+		if value, ok := label.(string); !ok || value == "" {
+			return fmt.Errorf("Could not validate mqtt worker as connection metricsLabel is not valid label name. (metrics_label: %v)", label)
+		}
+	}
+
+	if wildcard, ok := data["metricsWildcard"]; ok {
+		if index, ok := utils.AsInt(wildcard); !ok || index < 0 {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection metricsWildcard is not valid. It MUST NOT be negative. (metrics_wildcard: %v)",
+				wildcard,
+			)
+		}
+	}
+
+	if labels, ok := data["metricsMaxLabels"]; ok {
+		if max, ok := utils.AsInt(labels); !ok || max < 1 {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection metricsMaxLabels is not valid. It MUST be positive number. (metrics_max_labels: %v)",
+				labels,
+			)
+		}
+	}
+
+	if debug, ok := data["brokerDebug"]; ok {
+		if _, ok := debug.(bool); !ok {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection brokerDebug is not boolean. (broker_debug: %v)",
+				debug,
+			)
+		}
+	}
+
+	if jitter, ok := data["reconnectJitter"]; ok {
+		if _, ok := jitter.(bool); !ok {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection reconnectJitter is not boolean. (reconnect_jitter: %v)",
+				jitter,
+			)
+		}
+	}
+
+	if validate, ok := data["validateOnStart"]; ok {
+		if _, ok := validate.(bool); !ok {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection validateOnStart is not boolean. (validate_on_start: %v)",
+				validate,
+			)
+		}
+	}
+
+	if enabled, ok := data["connectedEvent"]; ok {
+		if _, ok := enabled.(bool); !ok {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection connectedEvent is not boolean. (connected_event: %v)",
+				enabled,
+			)
+		}
+	}
+
+	if enabled, ok := data["resolveBroker"]; ok {
+		if _, ok := enabled.(bool); !ok {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection resolveBroker is not boolean. (resolve_broker: %v)",
+				enabled,
+			)
+		}
+	}
+
+	if strict, ok := data["strictSubscribe"]; ok {
+		if _, ok := strict.(bool); !ok {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection strictSubscribe is not boolean. (strict_subscribe: %v)",
+				strict,
+			)
+		}
+	}
+
+	if size, ok := data["outboxSize"]; ok {
+		if max, ok := utils.AsInt(size); !ok || max < 1 {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection outboxSize is not valid. It MUST be positive number. (outbox_size: %v)",
+				size,
+			)
+		}
+	}
+
+	if size, ok := data["deadLetterSize"]; ok {
+		if max, ok := utils.AsInt(size); !ok || max < 1 {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection deadLetterSize is not valid. It MUST be positive number. (dead_letter_size: %v)",
+				size,
+			)
+		}
+	}
+
+	if codec, ok := data["deadLetterCodec"]; ok {
+		if name, ok := codec.(string); !ok || !utils.StringInSlice(name, AvailableDeadLetterCodecs) {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection deadLetterCodec is not valid. (dead_letter_codec: %v) - (available_codecs: %v)",
+				codec, AvailableDeadLetterCodecs,
+			)
+		}
+	}
+
+	if handlers, ok := data["maxConcurrentHandlers"]; ok {
+		if max, ok := utils.AsInt(handlers); !ok || max < 1 {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection maxConcurrentHandlers is not valid. It MUST be positive number. (max_concurrent_handlers: %v)",
+				handlers,
+			)
+		}
+	}
+
+	if ordering, ok := data["ordering"]; ok {
+		if _, ok := ordering.(string); !ok || !utils.StringInSlice(ordering.(string), AvailableOrderings) {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection ordering is not valid. (ordering: %v) - (available_orderings: %v)",
+				ordering, AvailableOrderings,
+			)
+		}
+	}
+
+	if delayOnStop, ok := data["delayOnStop"]; ok {
+		if _, ok := delayOnStop.(string); !ok || !utils.StringInSlice(delayOnStop.(string), AvailableDelayOnStop) {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection delayOnStop is not valid. (delay_on_stop: %v) - (available: %v)",
+				delayOnStop, AvailableDelayOnStop,
+			)
+		}
+	}
+
+	if size, ok := data["delayQueueSize"]; ok {
+		if value, ok := utils.AsInt(size); !ok || value < 1 {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection delayQueueSize is not valid. It MUST be positive number. (delay_queue_size: %v)",
+				size,
+			)
+		}
+	}
+
+	if poolSize, ok := data["poolSize"]; ok {
+		if size, ok := utils.AsInt(poolSize); !ok || size < 1 {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection poolSize is not valid. It MUST be positive number. (pool_size: %v)",
+				poolSize,
+			)
+		}
+	}
+
+	if bufferSize, ok := data["bufferSize"]; ok {
+		if size, ok := utils.AsInt(bufferSize); !ok || size < 1 {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection bufferSize is not valid. It MUST be positive number. (buffer_size: %v)",
+				bufferSize,
+			)
+		}
+	}
+
+	if maxSize, ok := data["recordMaxSize"]; ok {
+		if size, ok := utils.AsInt(maxSize); !ok || size < 0 {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection recordMaxSize is not valid. It MUST be number of bytes. (record_max_size: %v)",
+				maxSize,
+			)
+		}
+	}
+
+	if watermark, ok := data["slowConsumerWatermark"]; ok {
+		if value, ok := utils.AsInt(watermark); !ok || value < 1 {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection slowConsumerWatermark is not valid. It MUST be positive number. (slow_consumer_watermark: %v)",
+				watermark,
+			)
+		}
+	}
+
+	if threshold, ok := data["reconnectStormThreshold"]; ok {
+		if value, ok := utils.AsInt(threshold); !ok || value < 1 {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection reconnectStormThreshold is not valid. It MUST be positive number. (reconnect_storm_threshold: %v)",
+				threshold,
+			)
+		}
+	}
+
+	for _, key := range []string{"reconnectStormWindow", "recordMaxAge", "connectTimeout", "reconnectInterval", "busyReconnectInterval", "shutdownTimeout", "disconnectQuiesce", "subscribeTimeout", "maxEventAge", "slowConsumerDuration", "snapshotWindow", "publishTTL"} {
+		if value, ok := data[key]; ok {
+			if duration, ok := utils.AsDuration(value); !ok || duration <= 0 {
+				return fmt.Errorf(
+					"Could not validate mqtt worker as connection %s is not valid duration (e.g. \"10s\" or seconds). (value: %v)",
+					key, value,
+				)
+			}
+		}
+	}
+
+	if prefix, ok := data["topicPrefix"]; ok {
+		if _, ok := prefix.(string); !ok {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection topicPrefix is not string. (topic_prefix: %v)",
+				prefix,
+			)
+		}
+	}
+
+	if schema, ok := data["schema"]; ok {
+		if _, ok := schema.(string); !ok {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection schema is not string. (schema: %v)",
+				schema,
+			)
+		}
+
+		if _, err := events.GetValidator(schema.(string)); err != nil {
+			return fmt.Errorf("Could not validate mqtt worker as (err: %s)", err)
+		}
+	}
+
+	if err := c.ValidateTLS(data); err != nil {
+		return err
+	}
+
+	if store, ok := data["store"]; ok {
+		if _, ok := store.(string); !ok {
+			return fmt.Errorf(
+				"Could not validate mqtt worker as connection store is not valid. It MUST be either %q or directory path. (store: %v)",
+				MemoryStore, store,
+			)
+		}
+
+		if err := c.ValidateStore(store.(string)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateStore - Will ensure that file store directory exists and that we're
+// able to write into it
+func (c *Connection) ValidateStore(store string) error {
+	if store == MemoryStore {
+		return nil
+	}
+
+	info, err := os.Stat(store)
+
+	if err != nil {
+		return fmt.Errorf(
+			"Could not validate mqtt worker as connection store (directory: %s) is not accessible (err: %s)",
+			store, err,
+		)
+	}
+
+	if !info.IsDir() {
 		return fmt.Errorf(
-			"Could not validate mqtt worker as connection topic is not set. (connection_data: %q)",
-			data,
+			"Could not validate mqtt worker as connection store (path: %s) is not a directory",
+			store,
 		)
 	}
 
+	file, err := ioutil.TempFile(store, ".store-check-")
+
+	if err != nil {
+		return fmt.Errorf(
+			"Could not validate mqtt worker as connection store (directory: %s) is not writable (err: %s)",
+			store, err,
+		)
+	}
+
+	file.Close()
+	os.Remove(file.Name())
+
 	return nil
 }
 
+// connection - will return connection config block or empty one in case that
+// it's missing or malformed (Validate reports that)
+func (c *Connection) connection() map[string]interface{} {
+	if connection, ok := utils.AsStringMap(c.Config.Get("connection")); ok {
+		return connection
+	}
+
+	return map[string]interface{}{}
+}
+
 // GetBrokerAddr - will return full broker uri string (protocol://addr:port?params)
+// of the broker selected for current connection. First configured broker is
+// returned until connection is started.
 func (c *Connection) GetBrokerAddr() string {
-	connection := c.Config.Get("connection").(map[string]interface{})
-	return fmt.Sprintf("%s://%s?timeout=10s", connection["network"].(string), connection["address"].(string))
+	c.brokerLock.Lock()
+	address := c.broker
+	c.brokerLock.Unlock()
+
+	if addresses := c.GetBrokerAddresses(); address == "" && len(addresses) > 0 {
+		address = addresses[0].Address
+	}
+
+	return c.brokerURI(address)
+}
+
+// GetDefaultBrokerPort - will return port used when address has none, based on
+// connection network
+func (c *Connection) GetDefaultBrokerPort() int {
+	network, _ := utils.AsString(c.connection()["network"])
+
+	if port, ok := DefaultBrokerPorts[network]; ok {
+		return port
+	}
+
+	return DefaultBrokerPorts["tcp"]
 }
 
 // GetBrokerCredentials - will return username and password defined by config
 func (c *Connection) GetBrokerCredentials() (string, string) {
-	connection := c.Config.Get("connection").(map[string]interface{})
-	return connection["username"].(string), connection["password"].(string)
+	connection := c.connection()
+	username, _ := utils.AsString(connection["username"])
+	password, _ := utils.AsString(connection["password"])
+	return username, password
 }
 
 // GetBrokerClientID -
 func (c *Connection) GetBrokerClientID() string {
-	connection := c.Config.Get("connection").(map[string]interface{})
-	return connection["clientId"].(string)
+	connection := c.connection()
+	clientID, _ := utils.AsString(connection["clientId"])
+	return clientID
+}
+
+// GetBrokerStore - will return message store defined by config. In case that
+// store is not set, memory store will be used
+func (c *Connection) GetBrokerStore() MQTT.Store {
+	connection := c.connection()
+
+	if store, ok := connection["store"].(string); ok && store != MemoryStore {
+		return MQTT.NewFileStore(store)
+	}
+
+	return MQTT.NewMemoryStore()
+}
+
+// GetPayloadFormat - will return format of received payloads. Defaults to json.
+func (c *Connection) GetPayloadFormat() string {
+	connection := c.connection()
+
+	if format, ok := connection["payloadFormat"].(string); ok {
+		return format
+	}
+
+	return JSONPayloadFormat
+}
+
+// GetBufferSize - will return events channel buffer size. Connection
+// `bufferSize` config overrides PU_GO_MAX_CONCURRENCY derived default. It's
+// independent of process wide GOMAXPROCS.
+func (c *Connection) GetBufferSize() int {
+	if size, ok := utils.AsInt(c.connection()["bufferSize"]); ok && size > 0 {
+		return size
+	}
+
+	return utils.GetConcurrencyCount("PU_GO_MAX_CONCURRENCY")
+}
+
+// GetConnectTimeout - will return how long Start waits for initial connection
+func (c *Connection) GetConnectTimeout() time.Duration {
+	return c.getDuration("connectTimeout", InitialConnectionTimeout)
+}
+
+// GetReconnectInterval - will return how long to wait between connect attempts
+func (c *Connection) GetReconnectInterval() time.Duration {
+	return c.getDuration("reconnectInterval", ReconnectInterval)
+}
+
+// GetReconnectJitter - will return whenever reconnect interval is randomized (by
+// ReconnectJitter fraction) so reconnects of many workers spread out over time.
+// Defaults to true.
+func (c *Connection) GetReconnectJitter() bool {
+	connection := c.connection()
+
+	if jitter, ok := connection["reconnectJitter"].(bool); ok {
+		return jitter
+	}
+
+	return true
 }
 
-// GetBrokerTopicName -
+// GetBusyReconnectInterval - will return how long to wait before connecting
+// again once broker reported it's unavailable or busy
+func (c *Connection) GetBusyReconnectInterval() time.Duration {
+	return c.getDuration("busyReconnectInterval", BusyReconnectInterval)
+}
+
+// reconnectDelay - will return reconnect interval for the disconnect reason with
+// jitter applied
+func (c *Connection) reconnectDelay(reason error) time.Duration {
+	interval := c.GetReconnectInterval()
+
+	if isServerUnavailable(reason) {
+		interval = c.GetBusyReconnectInterval()
+	}
+
+	if !c.GetReconnectJitter() {
+		return interval
+	}
+
+	return interval + time.Duration((rand.Float64()*2-1)*ReconnectJitter*float64(interval))
+}
+
+// GetRecordMaxSize - will return size (bytes) recording capture is rotated at
+func (c *Connection) GetRecordMaxSize() int64 {
+	if size, ok := utils.AsInt(c.connection()["recordMaxSize"]); ok {
+		return int64(size)
+	}
+
+	return RecordMaxSize
+}
+
+// GetRecordMaxAge - will return age recording capture is rotated at
+func (c *Connection) GetRecordMaxAge() time.Duration {
+	return c.getDuration("recordMaxAge", RecordMaxAge)
+}
+
+// GetSubscribeTimeout - will return how long single subscribe attempt waits for
+// SUBACK
+func (c *Connection) GetSubscribeTimeout() time.Duration {
+	return c.getDuration("subscribeTimeout", SubscribeTimeout)
+}
+
+// GetShutdownTimeout - will return how long Stop waits for graceful disconnect
+func (c *Connection) GetShutdownTimeout() time.Duration {
+	return c.getDuration("shutdownTimeout", GracefulShutdownTimeout)
+}
+
+// GetDisconnectQuiesce - will return how long broker client may take to finish
+// in-flight work on disconnect
+func (c *Connection) GetDisconnectQuiesce() time.Duration {
+	return c.getDuration("disconnectQuiesce", DisconnectQuiesce)
+}
+
+// GetMaxEventAge - will return how old event may get before consumer drops it
+// instead of invoking handler. Not set (default) means events never go stale.
+func (c *Connection) GetMaxEventAge() (time.Duration, bool) {
+	age := c.getDuration("maxEventAge", 0)
+	return age, age > 0
+}
+
+// getDuration - will return connection config value as duration or default
+func (c *Connection) getDuration(key string, def time.Duration) time.Duration {
+	if duration, ok := utils.AsDuration(c.connection()[key]); ok && duration > 0 {
+		return duration
+	}
+
+	return def
+}
+
+// GetMaxConnectAttempts - will return how many consecutive connect attempts are
+// made before giving up. 0 (default) means that connecting is retried forever.
+func (c *Connection) GetMaxConnectAttempts() int {
+	connection := c.connection()
+
+	if max, ok := utils.AsInt(connection["maxConnectAttempts"]); ok && max > 0 {
+		return max
+	}
+
+	return 0
+}
+
+// GetUnitRules - will return unit normalization rules defined by `units`
+// config as list of {topic, field, from, to} objects
+func (c *Connection) GetUnitRules() ([]events.UnitRule, error) {
+	rules := []events.UnitRule{}
+	data, ok := c.connection()["units"].([]interface{})
+
+	if !ok {
+		if c.connection()["units"] == nil {
+			return rules, nil
+		}
+
+		return nil, fmt.Errorf("Could not read mqtt (worker: %s) units as they are not a list (units: %v)", c.Name(), c.connection()["units"])
+	}
+
+	for index, item := range data {
+		entry, ok := utils.AsStringMap(item)
+
+		if !ok {
+			return nil, fmt.Errorf("Could not read mqtt (worker: %s) unit (rule: %d) as it's not a map (entry: %v)", c.Name(), index, item)
+		}
+
+		rule := events.UnitRule{}
+		rule.Topic, _ = utils.AsString(entry["topic"])
+		rule.Field, _ = utils.AsString(entry["field"])
+		rule.From, _ = utils.AsString(entry["from"])
+		rule.To, _ = utils.AsString(entry["to"])
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// GetMaxReconnectAttempts - will return how many consecutive attempts are made
+// to reconnect once connection was lost before entering failed phase. 0
+// (default) means that reconnecting is retried forever.
+func (c *Connection) GetMaxReconnectAttempts() int {
+	if max, ok := utils.AsInt(c.connection()["maxReconnectAttempts"]); ok && max > 0 {
+		return max
+	}
+
+	return 0
+}
+
+// GetBrokerQoS - will return subscription qos defined by config. Defaults to 0.
+func (c *Connection) GetBrokerQoS() byte {
+	connection := c.connection()
+
+	if qos, ok := utils.AsInt(connection["qos"]); ok {
+		return byte(qos)
+	}
+
+	return 0
+}
+
+// GetValidateOnStart - will return whenever Start validates configuration
+// before connecting. Defaults to true.
+func (c *Connection) GetValidateOnStart() bool {
+	if validate, ok := c.connection()["validateOnStart"].(bool); ok {
+		return validate
+	}
+
+	return true
+}
+
+// GetStrictSubscribe - will return whenever Start should fail in case that broker
+// rejects subscription (e.g. due to ACL). Defaults to false (warning only).
+func (c *Connection) GetStrictSubscribe() bool {
+	connection := c.connection()
+	strict, _ := connection["strictSubscribe"].(bool)
+	return strict
+}
+
+// GetOrderMatters - will return whenever broker client must deliver messages in
+// order. Defaults to true.
+func (c *Connection) GetOrderMatters() bool {
+	connection := c.connection()
+
+	if orderMatters, ok := connection["orderMatters"].(bool); ok {
+		return orderMatters
+	}
+
+	return true
+}
+
+// GetOrdering - will return event processing ordering guarantee. Defaults to
+// parallel processing.
+func (c *Connection) GetOrdering() string {
+	connection := c.connection()
+
+	if ordering, ok := connection["ordering"].(string); ok {
+		return ordering
+	}
+
+	return ParallelOrdering
+}
+
+// GetDelayQueueSize - will return how many events may be delayed at once
+func (c *Connection) GetDelayQueueSize() int {
+	if size, ok := utils.AsInt(c.connection()["delayQueueSize"]); ok && size > 0 {
+		return size
+	}
+
+	return DelayQueueSize
+}
+
+// GetDelayOnStop - will return what happens with delayed events on Stop.
+// Defaults to discarding them.
+func (c *Connection) GetDelayOnStop() string {
+	if delayOnStop, ok := c.connection()["delayOnStop"].(string); ok {
+		return delayOnStop
+	}
+
+	return DiscardDelayed
+}
+
+// GetPoolSize - will return number of concurrent event processors used by Consume.
+// Defaults to number of CPUs regardless of GOMAXPROCS, so connections are tuned
+// by `poolSize` config independently of each other. Strict ordering always uses
+// single processor.
+func (c *Connection) GetPoolSize() int {
+	connection := c.connection()
+
+	if c.GetOrdering() == StrictOrdering {
+		return 1
+	}
+
+	if size, ok := utils.AsInt(connection["poolSize"]); ok && size > 0 {
+		return size
+	}
+
+	return runtime.NumCPU()
+}
+
+// GetMaxConcurrentHandlers - will return max number of simultaneously running
+// event handlers. Second value is false in case that it's not limited.
+func (c *Connection) GetMaxConcurrentHandlers() (int, bool) {
+	connection := c.connection()
+
+	if max, ok := utils.AsInt(connection["maxConcurrentHandlers"]); ok && max > 0 {
+		return max, true
+	}
+
+	return 0, false
+}
+
+// GetTopic - will return configured topic (without topicPrefix)
+func (c *Connection) GetTopic() string {
+	connection := c.connection()
+	topic, _ := utils.AsString(connection["topic"])
+	return topic
+}
+
+// GetTopics - will return additional topics defined by `topics` config
+func (c *Connection) GetTopics() []string {
+	topics := []string{}
+	list, _ := c.connection()["topics"].([]interface{})
+
+	for _, topic := range list {
+		if value, ok := topic.(string); ok && value != "" {
+			topics = append(topics, value)
+		}
+	}
+
+	return topics
+}
+
+// GetMaxTopicsPerSubscribe - will return how many topic filters are sent within
+// single SUBSCRIBE packet, as some brokers limit it
+func (c *Connection) GetMaxTopicsPerSubscribe() int {
+	if size, ok := utils.AsInt(c.connection()["maxTopicsPerSubscribe"]); ok && size > 0 {
+		return size
+	}
+
+	return MaxTopicsPerSubscribe
+}
+
+// GetDeferSubscribe - will return whenever configured topic is optional and
+// Start skips initial subscribe, leaving it to AddSubscription. Defaults to false.
+func (c *Connection) GetDeferSubscribe() bool {
+	connection := c.connection()
+	deferSubscribe, _ := connection["deferSubscribe"].(bool)
+	return deferSubscribe
+}
+
+// GetTopicPrefix - will return topic namespace (e.g. tenant) prepended to all
+// subscribed and published topics. Empty when not configured.
+func (c *Connection) GetTopicPrefix() string {
+	connection := c.connection()
+	prefix, _ := utils.AsString(connection["topicPrefix"])
+	return strings.Trim(prefix, "/")
+}
+
+// GetBrokerTopicName - will return full (prefixed) topic as known to the broker
 func (c *Connection) GetBrokerTopicName() string {
-	connection := c.Config.Get("connection").(map[string]interface{})
-	return connection["topic"].(string)
+	return c.PrefixTopic(c.GetTopic())
+}
+
+// PrefixTopic - will prepend topicPrefix to the topic
+func (c *Connection) PrefixTopic(topic string) string {
+	if prefix := c.GetTopicPrefix(); prefix != "" {
+		return prefix + "/" + topic
+	}
+
+	return topic
+}
+
+// StripTopic - will remove topicPrefix from the (broker) topic
+func (c *Connection) StripTopic(topic string) string {
+	if prefix := c.GetTopicPrefix(); prefix != "" {
+		return strings.TrimPrefix(topic, prefix+"/")
+	}
+
+	return topic
 }
 
 // Name -
 func (c *Connection) Name() string {
-	return c.Config.Get("name").(string)
+	name, _ := utils.AsString(c.Config.Get("name"))
+	return name
+}
+
+// Kind -
+func (c *Connection) Kind() string {
+	return Kind
+}
+
+// Status - Will return current connection status
+func (c *Connection) Status() string {
+	conn := c.client()
+
+	if conn == nil {
+		return managers.StatusStopped
+	}
+
+	if !conn.IsConnected() {
+		return managers.StatusDisconnected
+	}
+
+	return managers.StatusConnected
+}
+
+// Tags - Will return labels (region, environment, ...) configured by `tags`.
+// They are attached to logged entries and metrics of the connection.
+func (c *Connection) Tags() map[string]string {
+	return c.Config.GetTags()
+}
+
+// Healthy - Will return whenever connection is connected to the broker. Failed
+// connection (see `maxReconnectAttempts`) stays unhealthy until Reconnect.
+func (c *Connection) Healthy() bool {
+	conn := c.client()
+	return c.Phase() == managers.PhaseConnected && conn != nil && conn.IsConnected()
+}
+
+// Reconnect - Will revive connection which gave up (re)connecting and entered
+// failed phase by running connect loop again. Blocks the same way Start does.
+func (c *Connection) Reconnect() error {
+	if c.stopping() {
+		return fmt.Errorf("Could not reconnect mqtt (worker: %s) as connection is stopped", c.Name())
+	}
+
+	if phase := c.Phase(); phase != managers.PhaseFailed {
+		return fmt.Errorf("Could not reconnect mqtt (worker: %s) as connection is not failed (phase: %s)", c.Name(), phase)
+	}
+
+	c.Warning("Reconnecting failed mqtt (worker: %s) ...", c.Name())
+
+	c.stopReasonLock.Lock()
+	c.stopReason = managers.StopReason{}
+	c.stopReasonLock.Unlock()
+
+	opts, tlsConfig, err := c.clientOptions()
+
+	if err != nil {
+		return err
+	}
+
+	return c.connect(opts, tlsConfig)
+}
+
+// Describe - Will return snapshot of connection configuration and state
+func (c *Connection) Describe() connections.ConnectionInfo {
+	info := connections.ConnectionInfo{
+		Kind:   c.Kind(),
+		Name:   c.Name(),
+		Broker: c.GetBrokerAddr(),
+		Topics: c.brokerSubscriptions(),
+		QoS:    c.GetBrokerQoS(),
+		Status: c.Status(),
+		Phase:  c.Phase(),
+		Tags:   c.Tags(),
+		Config: c.Redacted(),
+	}
+
+	if info.Status == managers.StatusConnected {
+		c.connLock.Lock()
+		connectedAt := c.connectedAt
+		c.connLock.Unlock()
+
+		info.Connected = true
+		info.Uptime = time.Since(connectedAt)
+	}
+
+	return info
+}
+
+// Wait - Will block until all background goroutines (reconnect loop, liveness
+// checks and event processors) exited. They exit once connection is stopped or
+// done is signalled.
+func (c *Connection) Wait() {
+	c.routines.Wait()
+}
+
+// client - Will return paho client of the latest connect attempt, nil until
+// connection is started
+func (c *Connection) client() *MQTT.Client {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	return c.conn
+}
+
+// connected - Will return whenever paho client is connected to the broker
+func (c *Connection) connected() bool {
+	conn := c.client()
+	return conn != nil && conn.IsConnected()
+}
+
+// setClient - Will replace paho client used by the connection
+func (c *Connection) setClient(conn *MQTT.Client) {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	c.conn = conn
+}
+
+// stopping - Will return whenever Stop was called or done was signalled
+func (c *Connection) stopping() bool {
+	select {
+	case <-c.stop:
+		return true
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
 }
 
 // Adapter -
@@ -286,28 +1469,97 @@ func (c *Connection) Adapter() interface{} {
 
 // Stop - Will ensure that connection including subscription is killed allowing graceful timeout
 func (c *Connection) Stop() error {
-	c.Warning("Stopping mqtt (worker: %s) ...", c.Name())
+	return c.StopTimeout(c.GetShutdownTimeout())
+}
+
+// StopWithReason - Same as Stop but records why connection is stopped, see
+// StopReason
+func (c *Connection) StopWithReason(reason managers.StopReason) error {
+	c.setStopReason(reason)
+	return c.Stop()
+}
+
+// StopReason - Will return why connection stopped: shutdown, reload or fatal
+// error (broker unreachable, not authorized, ...) which made connection give up.
+// Reason is recorded before stop is signalled, so it's available to workers as
+// soon as they observe the stop. Zero value is returned while running.
+func (c *Connection) StopReason() managers.StopReason {
+	c.stopReasonLock.Lock()
+	defer c.stopReasonLock.Unlock()
+
+	return c.stopReason
+}
+
+// setStopReason - Will record stop reason unless one is recorded already
+func (c *Connection) setStopReason(reason managers.StopReason) {
+	c.stopReasonLock.Lock()
+	defer c.stopReasonLock.Unlock()
+
+	if !c.stopReason.Stopped() {
+		c.stopReason = reason
+	}
+}
+
+// StopTimeout - Same as Stop but whole operation (flush, unsubscribe and
+// disconnect) is bounded by passed timeout. Broker disconnect quiesce is
+// separate, smaller, value (see GetDisconnectQuiesce).
+func (c *Connection) StopTimeout(timeout time.Duration) error {
+	c.Warning("Stopping mqtt (worker: %s) (timeout: %s) ...", c.Name(), timeout)
+	defer c.SetPhase(managers.PhaseStopped)
+	defer c.StopRecording()
+	defer c.stopBrokerLogging()
+
+	c.setStopReason(managers.StopReason{Kind: managers.StopShutdown})
+	c.stopDelayed()
+	c.stopOnce.Do(func() { close(c.stop) })
+
+	conn := c.client()
 
-	if !c.conn.IsConnected() {
+	if conn == nil || !conn.IsConnected() {
 		c.Warning("Connection for mqtt (worker: %s) is already closed.", c.Name())
 		return nil
 	}
 
-	c.Warning("Unsubscribing from mqtt (worker: %s) (topic: %s)...", c.Name(), c.GetBrokerTopicName())
-	if token := c.conn.Unsubscribe(c.GetBrokerTopicName()); token.Wait() && token.Error() != nil {
-		c.Error(
-			"Could not unsubscribe from (topic: %s) for (worker: %s) due to (err: %s)",
-			c.GetBrokerTopicName(), c.Name(), token.Error(),
-		)
+	deadline := time.Now().Add(timeout)
+
+	if err := c.Flush(deadline.Sub(time.Now())); err != nil {
+		c.Error("Could not flush mqtt (worker: %s) pending publishes due to (err: %s)", c.Name(), err)
+	}
+
+	if topics := c.brokerSubscriptions(); len(topics) > 0 {
+		c.Warning("Unsubscribing from mqtt (worker: %s) (topics: %v)...", c.Name(), topics)
+		token := conn.Unsubscribe(topics...)
+
+		if !token.WaitTimeout(deadline.Sub(time.Now())) {
+			c.Error(
+				"Could not unsubscribe from (topics: %v) for (worker: %s) within (timeout: %s)",
+				topics, c.Name(), timeout,
+			)
+		} else if token.Error() != nil {
+			c.Error(
+				"Could not unsubscribe from (topics: %v) for (worker: %s) due to (err: %s)",
+				topics, c.Name(), token.Error(),
+			)
+		}
+	}
+
+	quiesce := c.GetDisconnectQuiesce()
+
+	if remaining := deadline.Sub(time.Now()); remaining < quiesce {
+		quiesce = remaining
+	}
+
+	if quiesce < 0 {
+		quiesce = 0
 	}
 
 	c.Warning(
-		"Stopping mqtt (worker: %s) connection (graceful_timeout: %ds)...",
-		c.Name(), GracefulShutdownTimeout,
+		"Stopping mqtt (worker: %s) connection (quiesce: %s)...",
+		c.Name(), quiesce,
 	)
 
-	c.conn.Disconnect(uint(GracefulShutdownTimeout))
-	time.Sleep(time.Duration(GracefulShutdownTimeout) * time.Second)
+	// Disconnect quiesce is in milliseconds and blocks for at most that long
+	conn.Disconnect(uint(quiesce / time.Millisecond))
 
 	return nil
 }
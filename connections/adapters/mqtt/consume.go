@@ -0,0 +1,269 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/powerunit-io/platform/events"
+	"github.com/powerunit-io/platform/metrics"
+)
+
+// Consume - Will start pool of event processors (sized by `poolSize` config)
+// invoking handler for each received event. Pool size is independent of event
+// buffer size. In case that `maxConcurrentHandlers` is configured, no more than
+// that many handlers run at once across all Consume calls. With `ordering:
+// strict` single processor is used so events are handled in order they were
+// received. Processors are stopped together with connection.
+func (c *Connection) Consume(handler events.Handler) error {
+	if err := c.claim(ChannelConsumer); err != nil {
+		return err
+	}
+
+	size := c.GetPoolSize()
+
+	// Slots are shared by all Consume calls, which may run concurrently
+	c.consumerLock.Lock()
+	if max, ok := c.GetMaxConcurrentHandlers(); ok && c.slots == nil {
+		c.slots = make(chan bool, max)
+	}
+	c.consumerLock.Unlock()
+
+	c.Info(
+		"Starting (pool_size: %d) event processors for mqtt (worker: %s) - (ordering: %s) ...",
+		size, c.Name(), c.GetOrdering(),
+	)
+
+	for i := 0; i < size; i++ {
+		c.routines.Add(1)
+		go c.process(handler)
+	}
+
+	return nil
+}
+
+// OnEvent - Will register callback invoked synchronously, from single internal
+// consumer goroutine, for each received event. It's an alternative to ranging
+// over DrainEvents, so ErrConsumerRegistered is returned in case that events are
+// already consumed by channel (DrainEvents, Consume) or by another callback.
+func (c *Connection) OnEvent(fn func(events.Event)) error {
+	if err := c.claim(CallbackConsumer); err != nil {
+		return err
+	}
+
+	c.routines.Add(1)
+
+	go func() {
+		defer c.routines.Done()
+
+		for {
+			select {
+			case event := <-c.events:
+				if !c.waitResumed() {
+					event.Release()
+					return
+				}
+
+				if !c.stale(event) {
+					fn(event)
+				}
+			case <-c.done:
+				return
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// PipeTo - Will make connection push events directly into channel owned by the
+// caller (e.g. shared by multiple connections) instead of the internal one, with
+// the same backpressure semantics. Events buffered before the call are forwarded
+// in background first and events received meanwhile are buffered behind them, so
+// order is kept. It's an alternative to DrainEvents, Consume and OnEvent, so
+// ErrConsumerRegistered is returned in case that any of them is already used.
+func (c *Connection) PipeTo(ch chan<- events.Event) error {
+	if err := c.claim(PipeConsumer); err != nil {
+		return err
+	}
+
+	c.routines.Add(1)
+
+	go func() {
+		defer c.routines.Done()
+
+		for {
+			// Sink is swapped only once no push is on its way into the buffer
+			// and buffer is empty, so no event is stranded there
+			c.consumerLock.Lock()
+			if c.pushing == 0 && len(c.events) == 0 {
+				c.sink = ch
+				c.consumerLock.Unlock()
+				return
+			}
+			c.consumerLock.Unlock()
+
+			select {
+			case event := <-c.events:
+				if !c.waitResumed() {
+					event.Release()
+					return
+				}
+
+				select {
+				case ch <- event:
+				case <-c.stop:
+					event.Release()
+					return
+				}
+			case <-time.After(DrainPollInterval):
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// push - Will push event to the caller channel in case that PipeTo is used or
+// to the internal one otherwise. Piped events are gated by Pause right here as
+// there is no internal consumer. Events still blocked on full channel once
+// connection is stopped are released.
+func (c *Connection) push(event events.Event) {
+	c.consumerLock.Lock()
+	sink := c.sink
+	if sink == nil {
+		c.pushing++
+	}
+	c.consumerLock.Unlock()
+
+	if sink == nil {
+		c.checkSlowConsumer()
+
+		select {
+		case c.events <- event:
+		case <-c.stop:
+			event.Release()
+		}
+
+		c.consumerLock.Lock()
+		c.pushing--
+		c.consumerLock.Unlock()
+		return
+	}
+
+	if !c.waitResumed() {
+		event.Release()
+		return
+	}
+
+	select {
+	case sink <- event:
+	case <-c.stop:
+		event.Release()
+	}
+}
+
+// claim - Will register way events are consumed. Channel consumers can be
+// claimed multiple times, callback and pipe only once and never together with
+// channel.
+func (c *Connection) claim(consumer string) error {
+	c.consumerLock.Lock()
+	defer c.consumerLock.Unlock()
+
+	if c.consumer == "" || (c.consumer == ChannelConsumer && consumer == ChannelConsumer) {
+		c.consumer = consumer
+		return nil
+	}
+
+	c.Error(
+		"Could not register mqtt (worker: %s) (consumer: %s) as events are already consumed by (consumer: %s)",
+		c.Name(), consumer, c.consumer,
+	)
+
+	return ErrConsumerRegistered
+}
+
+// process - Will invoke handler for each event until connection is stopped
+func (c *Connection) process(handler events.Handler) {
+	defer c.routines.Done()
+
+	for {
+		select {
+		case event := <-c.events:
+			if !c.waitResumed() {
+				event.Release()
+				return
+			}
+
+			c.handle(handler, event)
+		case <-c.done:
+			return
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// handle - Will invoke handler for single event. Events which failed processing
+// are pushed to dead letters so handler MUST NOT release them on error.
+func (c *Connection) handle(handler events.Handler, event events.Event) {
+	if c.stale(event) {
+		return
+	}
+
+	if c.slots != nil {
+		c.slots <- true
+		defer func() { <-c.slots }()
+	}
+
+	if err := handler(event); err != nil {
+		c.Error("Could not process event for mqtt (worker: %s) due to (err: %s)", c.Name(), err)
+		c.DeadLetter(event)
+	}
+}
+
+// stale - Will release and count event in case that it's older than configured
+// `maxEventAge`, e.g. when it's been sitting in the buffer during backlog
+func (c *Connection) stale(event events.Event) bool {
+	max, ok := c.GetMaxEventAge()
+
+	if !ok || event.Age() <= max {
+		return false
+	}
+
+	c.Debug(
+		"Dropping stale event for mqtt (worker: %s) - (age: %s) - (max_event_age: %s)",
+		c.Name(), event.Age(), max,
+	)
+
+	metrics.Inc(StaleEventsMetric, c.metricLabels())
+	event.Release()
+
+	return true
+}
+
+// Drain - Will wait for buffered events to be consumed or timeout to expire
+func (c *Connection) Drain(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for len(c.events) > 0 {
+		if time.Now().After(deadline) {
+			return fmt.Errorf(
+				"Could not drain mqtt (worker: %s) as (buffered: %d) events were not consumed within (timeout: %s)",
+				c.Name(), len(c.events), timeout,
+			)
+		}
+
+		time.Sleep(DrainPollInterval)
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"github.com/powerunit-io/platform/events"
+	"github.com/powerunit-io/platform/metrics"
+	"github.com/powerunit-io/platform/utils"
+)
+
+// deadLetters - Bounded ring buffer of events whose processing failed
+type deadLetters struct {
+	events []events.Event
+	next   int
+	full   bool
+}
+
+// DeadLetters - Will return events which failed processing, oldest first. Only
+// last `deadLetterSize` (config) events are kept.
+func (c *Connection) DeadLetters() []events.Event {
+	c.deadLettersLock.Lock()
+	defer c.deadLettersLock.Unlock()
+
+	if !c.deadLetters.full {
+		return append([]events.Event{}, c.deadLetters.events[:c.deadLetters.next]...)
+	}
+
+	return append(
+		append([]events.Event{}, c.deadLetters.events[c.deadLetters.next:]...),
+		c.deadLetters.events[:c.deadLetters.next]...,
+	)
+}
+
+// DeadLetter - Will push event into dead letter buffer (overwriting the oldest
+// one when buffer is full) and republish it to `deadLetterTopic` when configured.
+// Republished is the original payload or, with `deadLetterCodec` set, whole
+// event (original topic, receive time, ...) encoded by the codec. Dead letter
+// topic gets topicPrefix as any other publish. It MUST NOT be matched by any
+// subscription of the connection, which Validate rejects; dead letters are not
+// republished while subscription added at runtime matches it, as they would be
+// received and fail again.
+func (c *Connection) DeadLetter(event events.Event) {
+	metrics.Inc(DeadLettersMetric, c.metricLabels())
+
+	c.deadLettersLock.Lock()
+	if c.deadLetters.events == nil {
+		c.deadLetters.events = make([]events.Event, c.GetDeadLetterSize())
+	}
+
+	c.deadLetters.events[c.deadLetters.next] = event
+	c.deadLetters.next = (c.deadLetters.next + 1) % len(c.deadLetters.events)
+	c.deadLetters.full = c.deadLetters.full || c.deadLetters.next == 0
+	c.deadLettersLock.Unlock()
+
+	topic, ok := c.GetDeadLetterTopic()
+
+	if !ok || event.Message == nil {
+		return
+	}
+
+	if filter, loops := c.matchingSubscription(topic); loops {
+		c.Error(
+			"Not republishing dead letter for mqtt (worker: %s) as (topic: %s) is matched by its own (subscription: %s)",
+			c.Name(), topic, filter,
+		)
+		return
+	}
+
+	payload := event.Payload()
+
+	if codec, ok := c.GetDeadLetterCodec(); ok {
+		encoded, err := codec.Encode(event)
+
+		if err != nil {
+			c.Error("Could not encode dead letter for mqtt (worker: %s) due to (err: %s)", c.Name(), err)
+			return
+		}
+
+		payload = encoded
+	}
+
+	if err := c.Publish(topic, c.GetBrokerQoS(), false, payload); err != nil {
+		c.Error("Could not republish dead letter for mqtt (worker: %s) to (topic: %s) due to (err: %s)", c.Name(), topic, err)
+	}
+}
+
+// matchingSubscription - Will return subscription of the connection matching
+// topic published by it (topicPrefix included), if there is any
+func (c *Connection) matchingSubscription(topic string) (string, bool) {
+	for _, filter := range c.brokerSubscriptions() {
+		if _, ok := utils.MatchTopic(filter, c.PrefixTopic(topic)); ok {
+			return filter, true
+		}
+	}
+
+	return "", false
+}
+
+// GetDeadLetterSize - will return size of dead letter buffer. Defaults to
+// DefaultDeadLetterSize.
+func (c *Connection) GetDeadLetterSize() int {
+	connection := c.connection()
+
+	if size, ok := utils.AsInt(connection["deadLetterSize"]); ok && size > 0 {
+		return size
+	}
+
+	return DefaultDeadLetterSize
+}
+
+// GetDeadLetterTopic - will return topic dead letters are republished to. Second
+// value is false in case that it's not configured.
+func (c *Connection) GetDeadLetterTopic() (string, bool) {
+	connection := c.connection()
+	topic, ok := connection["deadLetterTopic"].(string)
+	return topic, ok
+}
+
+// GetDeadLetterCodec - will return codec republished dead letters are encoded
+// with. Second value is false in case that raw payload is republished.
+func (c *Connection) GetDeadLetterCodec() (events.Codec, bool) {
+	name, _ := utils.AsString(c.connection()["deadLetterCodec"])
+
+	if name == RawDeadLetterCodec {
+		return nil, false
+	}
+
+	return events.GetCodec(name)
+}
@@ -0,0 +1,122 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	MQTT "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
+)
+
+// brokerLog - Writer forwarding paho log lines to logger, at given level, of
+// the connection which enabled `brokerDebug` last and is still running
+type brokerLog int
+
+const (
+	brokerLogError brokerLog = iota
+	brokerLogWarning
+	brokerLogDebug
+)
+
+var (
+	// brokerLogTargets - Running connections with `brokerDebug` enabled
+	brokerLogTargets []*Connection
+
+	// brokerLogDefaults - Paho loggers replaced while any target is registered
+	brokerLogDefaults [4]*log.Logger
+
+	brokerLogLock sync.Mutex
+)
+
+// Write -
+func (l brokerLog) Write(p []byte) (int, error) {
+	brokerLogLock.Lock()
+	var target *Connection
+	if n := len(brokerLogTargets); n > 0 {
+		target = brokerLogTargets[n-1]
+	}
+	brokerLogLock.Unlock()
+
+	if target == nil {
+		return len(p), nil
+	}
+
+	line := strings.TrimRight(string(p), "\n")
+
+	switch l {
+	case brokerLogError:
+		target.Error("%s", line)
+	case brokerLogWarning:
+		target.Warning("%s", line)
+	default:
+		target.Debug("%s", line)
+	}
+
+	return len(p), nil
+}
+
+// SetupBrokerLogging - Will forward paho internal loggers into connection logger
+// in case that `brokerDebug` config is enabled. Paho loggers are process wide,
+// so they're replaced once the first such connection starts, forward to the one
+// started last and are restored once all of them are stopped.
+func (c *Connection) SetupBrokerLogging() {
+	if !c.GetBrokerDebug() {
+		return
+	}
+
+	c.Info("Forwarding mqtt client library logs for (worker: %s) ...", c.Name())
+
+	brokerLogLock.Lock()
+	defer brokerLogLock.Unlock()
+
+	brokerLogTargets = append(removeBrokerLogTarget(c), c)
+
+	if len(brokerLogTargets) == 1 {
+		brokerLogDefaults = [4]*log.Logger{MQTT.ERROR, MQTT.CRITICAL, MQTT.WARN, MQTT.DEBUG}
+
+		MQTT.ERROR = log.New(brokerLogError, BrokerLogPrefix, 0)
+		MQTT.CRITICAL = log.New(brokerLogError, BrokerLogPrefix, 0)
+		MQTT.WARN = log.New(brokerLogWarning, BrokerLogPrefix, 0)
+		MQTT.DEBUG = log.New(brokerLogDebug, BrokerLogPrefix, 0)
+	}
+}
+
+// stopBrokerLogging - Will stop forwarding paho logs into connection logger and
+// restore paho loggers once no other connection forwards them
+func (c *Connection) stopBrokerLogging() {
+	brokerLogLock.Lock()
+	defer brokerLogLock.Unlock()
+
+	registered := len(brokerLogTargets)
+	brokerLogTargets = removeBrokerLogTarget(c)
+
+	if registered > 0 && len(brokerLogTargets) == 0 {
+		MQTT.ERROR, MQTT.CRITICAL, MQTT.WARN, MQTT.DEBUG = brokerLogDefaults[0], brokerLogDefaults[1], brokerLogDefaults[2], brokerLogDefaults[3]
+	}
+}
+
+// removeBrokerLogTarget - Will return targets without the connection. MUST be
+// called with brokerLogLock held.
+func removeBrokerLogTarget(c *Connection) []*Connection {
+	targets := brokerLogTargets[:0]
+
+	for _, target := range brokerLogTargets {
+		if target != c {
+			targets = append(targets, target)
+		}
+	}
+
+	return targets
+}
+
+// GetBrokerDebug - will return whenever paho internal logs are forwarded.
+// Defaults to false.
+func (c *Connection) GetBrokerDebug() bool {
+	debug, _ := c.connection()["brokerDebug"].(bool)
+	return debug
+}
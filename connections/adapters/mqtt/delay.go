@@ -0,0 +1,114 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/powerunit-io/platform/events"
+)
+
+// delayedEvent - Event held by DelayEvent until its timer fires
+type delayedEvent struct {
+	event events.Event
+	timer *time.Timer
+}
+
+// DelayEvent - Will hold event for the duration and than push it into consume
+// path as if it was just received (debounce, scheduled retry, ...). Number of
+// delayed events is bounded by `delayQueueSize`, ErrDelayQueueFull is returned
+// once it's reached. On Stop delayed events are discarded or, with
+// `delayOnStop: flush`, pushed to dead letters (see DeadLetter) as consumers
+// are stopped too.
+func (c *Connection) DelayEvent(e events.Event, d time.Duration) error {
+	if c.stopping() {
+		return fmt.Errorf("Could not delay event for mqtt (worker: %s) as connection is stopped", c.Name())
+	}
+
+	c.delayedLock.Lock()
+	defer c.delayedLock.Unlock()
+
+	if len(c.delayed) >= c.GetDelayQueueSize() {
+		return ErrDelayQueueFull
+	}
+
+	if c.delayed == nil {
+		c.delayed = make(map[*delayedEvent]bool)
+	}
+
+	delayed := &delayedEvent{event: e}
+	delayed.timer = time.AfterFunc(d, func() {
+		if !c.undelay(delayed) {
+			return
+		}
+
+		if c.stopping() {
+			delayed.event.Release()
+			return
+		}
+
+		// Push gives up once connection is stopped meanwhile
+		c.push(delayed.event)
+	})
+
+	c.delayed[delayed] = true
+
+	return nil
+}
+
+// Delayed - Will return number of events currently delayed
+func (c *Connection) Delayed() int {
+	c.delayedLock.Lock()
+	defer c.delayedLock.Unlock()
+
+	return len(c.delayed)
+}
+
+// undelay - Will remove event from delay queue. False is returned in case that
+// it was already taken out of it by stopDelayed.
+func (c *Connection) undelay(delayed *delayedEvent) bool {
+	c.delayedLock.Lock()
+	defer c.delayedLock.Unlock()
+
+	if !c.delayed[delayed] {
+		return false
+	}
+
+	delete(c.delayed, delayed)
+	return true
+}
+
+// stopDelayed - Will stop delay timers and flush delayed events to dead letters
+// or discard them according to `delayOnStop`
+func (c *Connection) stopDelayed() {
+	c.delayedLock.Lock()
+	pending := c.delayed
+	c.delayed = nil
+	c.delayedLock.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	flush := c.GetDelayOnStop() == FlushDelayed
+
+	for delayed := range pending {
+		delayed.timer.Stop()
+
+		if flush {
+			c.DeadLetter(delayed.event)
+			continue
+		}
+
+		delayed.event.Release()
+	}
+
+	c.Warning(
+		"Stopped (delayed: %d) events for mqtt (worker: %s) - (delay_on_stop: %s)",
+		len(pending), c.Name(), c.GetDelayOnStop(),
+	)
+}
@@ -0,0 +1,84 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"strings"
+
+	MQTT "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
+)
+
+// ConnectionLostHandler - Will record reason of unexpected disconnect and count
+// it towards reconnect storm detection. Reason decides how the connect loop
+// proceeds: not authorized stops reconnecting, server unavailable backs off by
+// `busyReconnectInterval`.
+func (c *Connection) ConnectionLostHandler(client *MQTT.Client, err error) {
+	c.Warning("Lost mqtt connection for (worker: %s) due to (reason: %s)", c.Name(), err)
+
+	if isNotAuthorized(err) {
+		c.Error("Mqtt (worker: %s) lost connection as not authorized", c.Name())
+	} else if isServerUnavailable(err) {
+		c.Warning("Mqtt (worker: %s) lost connection as broker is unavailable. Backing off for (interval: %s) ...", c.Name(), c.GetBusyReconnectInterval())
+	}
+
+	c.setDisconnectReason(err)
+	c.Disconnected()
+	c.countReconnect()
+
+	// Client library keeps reporting lost client as connected, with its workers
+	// running, unless it reconnects on its own. Dropping it lets connect loop
+	// notice the loss and reconnect.
+	if client != nil {
+		client.ForceDisconnect()
+	}
+}
+
+// LastDisconnectReason - Will return reason of the last lost connection or
+// refused connect attempt. Nil in case that connection was never lost.
+func (c *Connection) LastDisconnectReason() error {
+	c.disconnectReasonLock.Lock()
+	defer c.disconnectReasonLock.Unlock()
+
+	return c.disconnectReason
+}
+
+// setDisconnectReason -
+func (c *Connection) setDisconnectReason(err error) {
+	c.disconnectReasonLock.Lock()
+	defer c.disconnectReasonLock.Unlock()
+
+	c.disconnectReason = err
+}
+
+// isNotAuthorized - Check whenever broker refused connection due to credentials
+// or authorization (MQTT 3.1.1 CONNACK return codes 4 and 5). Reconnecting in
+// that case would only hammer the broker.
+func isNotAuthorized(err error) bool {
+	return hasReason(err, NotAuthorizedReasons)
+}
+
+// isServerUnavailable - Check whenever broker refused or dropped connection as
+// unavailable or busy (MQTT 3.1.1 CONNACK return code 3)
+func isServerUnavailable(err error) bool {
+	return hasReason(err, ServerUnavailableReasons)
+}
+
+// hasReason - Check whenever error contains any of the lower cased reasons
+func hasReason(err error, reasons []string) bool {
+	if err == nil {
+		return false
+	}
+
+	reason := strings.ToLower(err.Error())
+
+	for _, fragment := range reasons {
+		if strings.Contains(reason, fragment) {
+			return true
+		}
+	}
+
+	return false
+}
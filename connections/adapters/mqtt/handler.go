@@ -0,0 +1,224 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/powerunit-io/platform/events"
+	"github.com/powerunit-io/platform/metrics"
+	"github.com/powerunit-io/platform/utils"
+
+	MQTT "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
+)
+
+// BrokerHandler - Will turn received message into event. Nil messages are
+// ignored so no event without message ever reaches the consumers.
+func (c *Connection) BrokerHandler(client *MQTT.Client, msg MQTT.Message) {
+	if msg == nil {
+		return
+	}
+
+	c.lastMessageLock.Lock()
+	c.lastMessageAt = time.Now()
+	c.lastMessageLock.Unlock()
+
+	for _, tap := range c.taps {
+		tap(msg.Topic(), msg.Payload())
+	}
+
+	c.CountReceived(msg.Topic())
+	c.countSubscriptions(msg.Topic())
+
+	c.Info(
+		"Received new mqtt (worker: %s) - (message: %s) for (topic: %s). Building event now ...",
+		c.Name(), msg.Payload(), msg.Topic(),
+	)
+
+	msg = withTopic(msg, c.StripTopic(msg.Topic()))
+
+	if !c.allowed(msg.Topic()) {
+		metrics.Inc(DeniedMessagesMetric, c.metricLabels())
+		c.Warning("Dropping mqtt (worker: %s) message on not allowed (topic: %s)", c.Name(), msg.Topic())
+		return
+	}
+
+	c.record(msg)
+
+	msg, ok := c.decode(msg)
+
+	if !ok {
+		return
+	}
+
+	if c.GetPayloadFormat() == NDJSONPayloadFormat {
+		for _, line := range splitLines(msg) {
+			c.Emit(line)
+		}
+
+		return
+	}
+
+	c.Emit(msg)
+}
+
+// decode - Will run received message through payload decoding and decryption.
+// Failures are logged and counted, and false is returned so message is dropped.
+func (c *Connection) decode(msg MQTT.Message) (MQTT.Message, bool) {
+	decoded, metric, err := c.unwrap(msg)
+
+	if err != nil {
+		metrics.Inc(metric, c.metricLabels())
+		c.Error("Dropping mqtt (worker: %s) message on (topic: %s) due to (err: %s)", c.Name(), msg.Topic(), err)
+		return nil, false
+	}
+
+	return decoded, true
+}
+
+// unwrap - Will run received message through payload decoding and decryption,
+// returning metric counting the failure together with the error
+func (c *Connection) unwrap(msg MQTT.Message) (MQTT.Message, string, error) {
+	msg, err := c.decodePayload(msg)
+
+	if err != nil {
+		return nil, PayloadDecodeFailuresMetric, err
+	}
+
+	if c.decryptor != nil {
+		plaintext, err := c.decryptor(msg.Topic(), msg.Payload())
+
+		if err != nil {
+			return nil, DecryptFailuresMetric, fmt.Errorf("Could not decrypt message due to (err: %s)", err)
+		}
+
+		msg = &payloadMessage{Message: msg, payload: plaintext}
+	}
+
+	return msg, "", nil
+}
+
+// Emit - Will build event out of the message and push it to the events channel.
+// Messages rejected by validator are dropped and counted.
+func (c *Connection) Emit(msg MQTT.Message) {
+	if err := c.validate(msg); err != nil {
+		metrics.Inc(InvalidMessagesMetric, c.metricLabels())
+		c.Error("Dropping invalid mqtt (worker: %s) message on (topic: %s) due to (err: %s)", c.Name(), msg.Topic(), err)
+		return
+	}
+
+	event, err := events.NewEvent(msg)
+
+	if err != nil {
+		c.Error("Could not handle received event due to (err: %s)", err)
+		event.Release()
+		return
+	}
+
+	event.ConnValues = c.connValues()
+
+	if err = c.types.Decode(&event); err != nil {
+		metrics.Inc(DecodeFailuresMetric, c.metricLabels())
+		c.Error("Dropping event for mqtt (worker: %s) due to (err: %s)", c.Name(), err)
+		event.Release()
+		return
+	}
+
+	for _, transform := range c.transforms {
+		if event, err = transform(event); err != nil {
+			c.Error("Dropping event for mqtt (worker: %s) as transform failed due to (err: %s)", c.Name(), err)
+			event.Release()
+			return
+		}
+	}
+
+	// Transforms may only drop events by error, never by returning empty one
+	if event.Message == nil {
+		c.Error("Dropping event for mqtt (worker: %s) as transform returned event without message", c.Name())
+		event.Release()
+		return
+	}
+
+	c.Info("Event successfully created (data: %v)", event)
+
+	if c.collectSnapshot(event) {
+		return
+	}
+
+	c.push(event)
+}
+
+// LastMessageTime - Will return when the last message was received from the
+// broker (zero time when none was received yet). Any message counts, including
+// ones which are later dropped, so it tells silent devices apart from invalid
+// ones.
+func (c *Connection) LastMessageTime() time.Time {
+	c.lastMessageLock.Lock()
+	defer c.lastMessageLock.Unlock()
+
+	return c.lastMessageAt
+}
+
+// Tap - Will register observer of raw inbound messages. Taps are invoked with
+// broker topic (including topicPrefix) and payload before any processing, so
+// they see messages which later fail validation or event conversion as well.
+// Tap MUST NOT modify payload.
+func (c *Connection) Tap(fn func(topic string, payload []byte)) {
+	c.taps = append(c.taps, fn)
+}
+
+// RegisterType - Will register Go type events arriving on topics matching the
+// filter are decoded into, see events.Event.Decoded. Events failing decoding
+// are dropped and counted.
+func (c *Connection) RegisterType(filter string, prototype interface{}) {
+	c.types.Register(filter, prototype)
+}
+
+// SetDecryptor - Will set decryptor applied to every received payload before
+// it's validated and converted into event. Recorded messages stay encrypted.
+func (c *Connection) SetDecryptor(decryptor events.Decryptor) {
+	c.decryptor = decryptor
+}
+
+// SetEncryptor - Will set encryptor applied to every published payload
+func (c *Connection) SetEncryptor(encryptor events.Encryptor) {
+	c.encryptor = encryptor
+}
+
+// SetValidator - Will set validator which every received payload must pass
+// before being converted into event. It takes precedence over `schema` config.
+func (c *Connection) SetValidator(validator events.Validator) {
+	c.validator = validator
+}
+
+// validate - Will run message through validator, if there is any
+func (c *Connection) validate(msg MQTT.Message) error {
+	validator := c.validator
+
+	if validator == nil {
+		schema, ok := utils.AsString(c.connection()["schema"])
+
+		if !ok {
+			return nil
+		}
+
+		var err error
+
+		if validator, err = events.GetValidator(schema); err != nil {
+			return err
+		}
+	}
+
+	return validator(msg.Topic(), msg.Payload())
+}
+
+// Use - Will register event transform. Transforms are applied to every event in
+// order they were registered, before event is pushed to the events channel.
+// Event is dropped in case that transform returns error.
+func (c *Connection) Use(transform events.Transform) {
+	c.transforms = append(c.transforms, transform)
+}
@@ -6,7 +6,11 @@
 package mqtt
 
 import (
+	"context"
+	"time"
+
 	"github.com/powerunit-io/platform/config"
+	"github.com/powerunit-io/platform/connections"
 	"github.com/powerunit-io/platform/events"
 	"github.com/powerunit-io/platform/logging"
 	"github.com/powerunit-io/platform/managers"
@@ -14,22 +18,86 @@ import (
 
 // Adapter -
 type Adapter interface {
-	managers.Service
+	connections.Connection
 
 	DrainEvents() chan events.Event
+	Stream() *events.Stream
+	WaitForMessage(timeout time.Duration) (events.Event, error)
+	WaitForMessageMatching(pred func(events.Event) bool, timeout time.Duration) (events.Event, error)
+	Consume(handler events.Handler) error
+	OnEvent(fn func(events.Event)) error
+	ConsumeSharded(n int, keyFn func(events.Event) string, fn func(events.Event)) error
+	PipeTo(ch chan<- events.Event) error
+	DelayEvent(e events.Event, d time.Duration) error
+	Pause()
+	Resume()
+	Paused() bool
+	Use(transform events.Transform)
+	RegisterType(filter string, prototype interface{})
+	Tap(fn func(topic string, payload []byte))
+	SetDecryptor(decryptor events.Decryptor)
+	SetEncryptor(encryptor events.Encryptor)
+	StartRecording(path string) error
+	StopRecording() error
+	DeadLetters() []events.Event
+	OnInitialSnapshot(fn func([]events.Event))
+	WithValue(key string, value interface{})
+	AddSubscription(topic string) error
+	Subscriptions() []string
+	Tags() map[string]string
+	SetElector(elector Elector)
+	SetResolver(resolver Resolver)
+	Leader() bool
+	Healthy() bool
+	LastMessageTime() time.Time
+	Reconnect() error
+	GrantedQoS(topic string) (byte, bool)
+	SubscriptionStats() []SubscriptionStat
+	SubscribeWithResult(topic string, qos byte) (byte, error)
+	LastDisconnectReason() error
+	OnReconnectStorm(fn func(count int, window time.Duration))
+	OnSlowConsumer(fn func(depth int, duration time.Duration))
+	Phase() managers.Phase
+	Clone() (Adapter, error)
+	Request(ctx context.Context, reqTopic string, payload map[string]interface{}, respTopic string) (events.Event, error)
+
+	Publish(topic string, qos byte, retained bool, payload interface{}) error
+	PublishWithTTL(topic string, qos byte, retained bool, payload interface{}, ttl time.Duration) error
+	OnPublishExpired(fn func(topic string))
+	Flush(timeout time.Duration) error
+	StopTimeout(timeout time.Duration) error
+	StopWithReason(reason managers.StopReason) error
+	StopReason() managers.StopReason
+	Wait()
 }
 
 // NewAdapter -
 func NewAdapter(n string, conf map[string]interface{}, logger *logging.Logger) (Adapter, error) {
 
-	cnf, err := config.NewConfigManager(n, conf)
+	cnf, name, err := config.NewNamedConfigManager(n, conf)
 
 	if err != nil {
 		logger.Error("Failed to configure mqtt configuration manager for (manager: %s) (error: %s)", n, err)
 		return nil, err
 	}
 
-	cnf.Set("name", n)
+	cnf.Set("name", name)
+
+	if tags := cnf.GetTags(); len(tags) > 0 {
+		logger = logger.WithTags(tags)
+	}
+
+	connection := &Connection{Logger: logger, Config: cnf, stop: make(chan bool)}
+	connection.events = make(chan events.Event, connection.GetBufferSize())
+
+	// Unit normalization is first of the transforms so that ones registered
+	// by workers always see canonical units. Invalid rules are reported by
+	// Validate.
+	if rules, err := connection.GetUnitRules(); err == nil && len(rules) > 0 {
+		if transform, err := events.NormalizeUnits(rules); err == nil {
+			connection.Use(transform)
+		}
+	}
 
-	return Adapter(&Connection{Logger: logger, Config: cnf}), nil
+	return Adapter(connection), nil
 }
@@ -0,0 +1,89 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import "time"
+
+// Elector - Pluggable lock deciding which of the replicas is the leader (e.g.
+// backed by broker retained topic, database or external coordinator)
+type Elector interface {
+	// Leader - Will return whenever this replica currently holds the lock
+	Leader() bool
+}
+
+// SetElector - Will gate subscriptions by leader election. Connection of the
+// non leader stays connected but idle (without subscriptions) and subscribes as
+// soon as it takes over, see LeaderCheckInterval. MUST be set before Start.
+func (c *Connection) SetElector(elector Elector) {
+	c.elector = elector
+}
+
+// Leader - Will return whenever connection should actively subscribe and
+// process events. Always true in case that elector is not set.
+func (c *Connection) Leader() bool {
+	return c.elector == nil || c.elector.Leader()
+}
+
+// watchLeadership - Will subscribe once leadership is taken over and
+// unsubscribe once it's lost. Subscribing on (re)connect is gated by
+// subscribeAll.
+func (c *Connection) watchLeadership() {
+	defer c.routines.Done()
+
+	ticker := time.NewTicker(LeaderCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.stop:
+			return
+		}
+
+		if !c.connected() {
+			continue
+		}
+
+		leader := c.Leader()
+
+		if leader == c.leading() {
+			continue
+		}
+
+		if leader {
+			c.Warning("Mqtt (worker: %s) took over leadership. Subscribing ...", c.Name())
+			c.subscribeAll()
+			continue
+		}
+
+		c.Warning("Mqtt (worker: %s) lost leadership. Unsubscribing and staying idle ...", c.Name())
+		c.setLeading(false)
+
+		if topics := c.brokerSubscriptions(); len(topics) > 0 {
+			token := c.client().Unsubscribe(topics...)
+
+			if !token.WaitTimeout(c.GetSubscribeTimeout()) || token.Error() != nil {
+				c.Error("Could not unsubscribe from (topics: %v) for (worker: %s) after leadership was lost", topics, c.Name())
+			}
+		}
+	}
+}
+
+// leading - Will return whenever connection is subscribed as leader
+func (c *Connection) leading() bool {
+	c.leaderLock.Lock()
+	defer c.leaderLock.Unlock()
+
+	return c.isLeading
+}
+
+// setLeading -
+func (c *Connection) setLeading(leading bool) {
+	c.leaderLock.Lock()
+	defer c.leaderLock.Unlock()
+
+	c.isLeading = leading
+}
@@ -0,0 +1,63 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"github.com/powerunit-io/platform/metrics"
+	"github.com/powerunit-io/platform/utils"
+)
+
+// SetupMetrics - Will apply metrics cardinality guard defined by config
+func (c *Connection) SetupMetrics() {
+	connection := c.connection()
+
+	if max, ok := utils.AsInt(connection["metricsMaxLabels"]); ok {
+		metrics.SetMaxSeries(EventsReceivedMetric, max)
+	}
+}
+
+// CountReceived - Will count received message. In case that `metricsLabel` is
+// configured, label value is taken from the wildcard (`metricsWildcard` index,
+// first one by default) matched within concrete topic. Otherwise message is
+// labelled by subscription topic filter it matched (any of Subscriptions).
+func (c *Connection) CountReceived(topic string) {
+	connection := c.connection()
+	filter := metrics.OverflowLabelValue
+
+	for _, subscription := range c.brokerSubscriptions() {
+		if _, ok := utils.MatchTopic(subscription, topic); ok {
+			filter = subscription
+			break
+		}
+	}
+
+	labels := c.metricLabels()
+	labels["topic"] = filter
+
+	if label, ok := connection["metricsLabel"].(string); ok {
+		index, _ := utils.AsInt(connection["metricsWildcard"])
+		captures, _ := utils.MatchTopic(filter, topic)
+
+		value := metrics.OverflowLabelValue
+		if index >= 0 && index < len(captures) {
+			value = captures[index]
+		}
+
+		labels = c.metricLabels()
+		labels[label] = value
+	}
+
+	metrics.Inc(EventsReceivedMetric, labels)
+}
+
+// metricLabels - Will return labels every connection metric is seeded with,
+// connection name and configured tags
+func (c *Connection) metricLabels() map[string]string {
+	labels := c.Tags()
+	labels["connection"] = c.Name()
+
+	return labels
+}
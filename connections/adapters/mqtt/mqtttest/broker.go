@@ -0,0 +1,434 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtttest ...
+package mqtttest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/powerunit-io/platform/utils"
+)
+
+// mqtt control packet types used by the test broker
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetPubrec      = 5
+	packetPubrel      = 6
+	packetPubcomp     = 7
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetUnsubscribe = 10
+	packetUnsuback    = 11
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+)
+
+// NewBroker - Will start minimal in-memory MQTT 3.1/3.1.1 broker listening
+// on loopback and return its address (usable as `address` config) together
+// with function stopping it. Broker accepts any credentials and supports
+// subscribe/unsubscribe with wildcards, retained messages and QoS 0, 1 and 2
+// delivery. Stopping the broker drops all clients, so together with Listen it
+// can be used to exercise reconnects as well. Intended for tests only.
+func NewBroker(t testing.TB) (string, func()) {
+	return Listen(t, "127.0.0.1:0")
+}
+
+// Listen - Same as NewBroker but broker listens on the address, e.g. one of the
+// broker stopped before so that clients reconnect to it
+func Listen(t testing.TB, addr string) (string, func()) {
+	listener, err := net.Listen("tcp", addr)
+
+	if err != nil {
+		t.Fatalf("Could not start mqtt test broker on (addr: %s) due to (err: %s)", addr, err)
+	}
+
+	broker := &testBroker{
+		listener: listener,
+		clients:  make(map[*testClient]bool),
+		retained: make(map[string]testPublish),
+	}
+
+	go broker.serve()
+
+	var once sync.Once
+
+	return listener.Addr().String(), func() { once.Do(broker.stop) }
+}
+
+// testBroker -
+type testBroker struct {
+	listener net.Listener
+	clients  map[*testClient]bool
+	retained map[string]testPublish
+	lock     sync.Mutex
+}
+
+// testClient - Single connected client of the test broker
+type testClient struct {
+	conn          net.Conn
+	subscriptions map[string]byte
+	nextID        uint16
+	writeLock     sync.Mutex
+}
+
+// testPublish -
+type testPublish struct {
+	topic   string
+	payload []byte
+	qos     byte
+}
+
+// serve - Will accept clients until listener is closed
+func (b *testBroker) serve() {
+	for {
+		conn, err := b.listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		client := &testClient{conn: conn, subscriptions: make(map[string]byte)}
+
+		b.lock.Lock()
+		b.clients[client] = true
+		b.lock.Unlock()
+
+		go b.handle(client)
+	}
+}
+
+// stop - Will close listener and drop all clients
+func (b *testBroker) stop() {
+	b.listener.Close()
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for client := range b.clients {
+		client.conn.Close()
+	}
+}
+
+// handle - Will process packets of the client until it disconnects
+func (b *testBroker) handle(client *testClient) {
+	defer func() {
+		client.conn.Close()
+
+		b.lock.Lock()
+		delete(b.clients, client)
+		b.lock.Unlock()
+	}()
+
+	r := bufio.NewReader(client.conn)
+
+	for {
+		header, body, err := readPacket(r)
+
+		if err != nil {
+			return
+		}
+
+		switch header >> 4 {
+		case packetConnect:
+			client.write(packetConnack<<4, []byte{0, 0})
+		case packetPublish:
+			publish, id, err := parsePublish(header, body)
+
+			if err != nil {
+				return
+			}
+
+			switch publish.qos {
+			case 1:
+				client.write(packetPuback<<4, id)
+			case 2:
+				client.write(packetPubrec<<4, id)
+			}
+
+			b.publish(publish, header&0x01 == 1)
+		case packetPubrec:
+			// Second step of QoS 2 delivery to the client
+			client.write(packetPubrel<<4|0x02, body)
+		case packetPubrel:
+			client.write(packetPubcomp<<4, body)
+		case packetSubscribe:
+			b.subscribe(client, body)
+		case packetUnsubscribe:
+			b.unsubscribe(client, body)
+		case packetPingreq:
+			client.write(packetPingresp<<4, nil)
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+// publish - Will deliver message to all matching subscriptions and keep it in
+// case that it's retained (empty retained payload clears the topic)
+func (b *testBroker) publish(publish testPublish, retain bool) {
+	b.lock.Lock()
+
+	if retain {
+		if len(publish.payload) == 0 {
+			delete(b.retained, publish.topic)
+		} else {
+			b.retained[publish.topic] = publish
+		}
+	}
+
+	clients := make([]*testClient, 0, len(b.clients))
+	for client := range b.clients {
+		clients = append(clients, client)
+	}
+
+	b.lock.Unlock()
+
+	for _, client := range clients {
+		client.deliver(publish, false)
+	}
+}
+
+// subscribe - Will register subscriptions, acknowledge them and deliver
+// matching retained messages
+func (b *testBroker) subscribe(client *testClient, body []byte) {
+	if len(body) < 2 {
+		return
+	}
+
+	ack := append([]byte{}, body[:2]...)
+	filters := []string{}
+
+	for rest := body[2:]; len(rest) > 0; {
+		filter, tail, err := readString(rest)
+
+		if err != nil || len(tail) < 1 {
+			return
+		}
+
+		qos := tail[0] & 0x03
+		if qos > 2 {
+			qos = 2
+		}
+
+		client.writeLock.Lock()
+		client.subscriptions[filter] = qos
+		client.writeLock.Unlock()
+
+		ack = append(ack, qos)
+		filters = append(filters, filter)
+		rest = tail[1:]
+	}
+
+	client.write(packetSuback<<4, ack)
+
+	b.lock.Lock()
+	retained := []testPublish{}
+	for _, publish := range b.retained {
+		for _, filter := range filters {
+			if _, ok := utils.MatchTopic(filter, publish.topic); ok {
+				retained = append(retained, publish)
+				break
+			}
+		}
+	}
+	b.lock.Unlock()
+
+	for _, publish := range retained {
+		client.deliver(publish, true)
+	}
+}
+
+// unsubscribe - Will remove subscriptions and acknowledge it
+func (b *testBroker) unsubscribe(client *testClient, body []byte) {
+	if len(body) < 2 {
+		return
+	}
+
+	for rest := body[2:]; len(rest) > 0; {
+		filter, tail, err := readString(rest)
+
+		if err != nil {
+			return
+		}
+
+		client.writeLock.Lock()
+		delete(client.subscriptions, filter)
+		client.writeLock.Unlock()
+
+		rest = tail
+	}
+
+	client.write(packetUnsuback<<4, body[:2])
+}
+
+// deliver - Will send message to the client in case that any of its
+// subscriptions matches, with the highest matching granted qos
+func (c *testClient) deliver(publish testPublish, retained bool) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	qos, matched := byte(0), false
+
+	for filter, granted := range c.subscriptions {
+		if _, ok := utils.MatchTopic(filter, publish.topic); ok {
+			matched = true
+
+			if granted > qos {
+				qos = granted
+			}
+		}
+	}
+
+	if !matched {
+		return
+	}
+
+	if publish.qos < qos {
+		qos = publish.qos
+	}
+
+	header := byte(packetPublish<<4) | qos<<1
+	if retained {
+		header |= 0x01
+	}
+
+	body := appendString(nil, publish.topic)
+
+	if qos > 0 {
+		c.nextID++
+		if c.nextID == 0 {
+			c.nextID = 1
+		}
+
+		body = append(body, byte(c.nextID>>8), byte(c.nextID))
+	}
+
+	writePacket(c.conn, header, append(body, publish.payload...))
+}
+
+// write - Will send packet to the client
+func (c *testClient) write(header byte, body []byte) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	writePacket(c.conn, header, body)
+}
+
+// parsePublish - Will parse PUBLISH packet returning its packet id (empty for
+// QoS 0)
+func parsePublish(header byte, body []byte) (testPublish, []byte, error) {
+	topic, rest, err := readString(body)
+
+	if err != nil {
+		return testPublish{}, nil, err
+	}
+
+	publish := testPublish{topic: topic, qos: (header >> 1) & 0x03}
+	var id []byte
+
+	if publish.qos > 0 {
+		if len(rest) < 2 {
+			return testPublish{}, nil, io.ErrUnexpectedEOF
+		}
+
+		id, rest = rest[:2], rest[2:]
+	}
+
+	publish.payload = append([]byte{}, rest...)
+
+	return publish, id, nil
+}
+
+// readPacket - Will read fixed header and body of the next packet
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length, multiplier := 0, 1
+
+	for i := 0; ; i++ {
+		digit, err := r.ReadByte()
+
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if i >= 4 {
+			return 0, nil, fmt.Errorf("Could not read mqtt packet as remaining length is malformed")
+		}
+
+		length += int(digit&0x7f) * multiplier
+		multiplier *= 128
+
+		if digit&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+
+	return header, body, nil
+}
+
+// writePacket - Will write packet with remaining length encoded
+func writePacket(w io.Writer, header byte, body []byte) error {
+	packet := []byte{header}
+
+	for length := len(body); ; {
+		digit := byte(length % 128)
+		length /= 128
+
+		if length > 0 {
+			digit |= 0x80
+		}
+
+		packet = append(packet, digit)
+
+		if length == 0 {
+			break
+		}
+	}
+
+	_, err := w.Write(append(packet, body...))
+	return err
+}
+
+// readString - Will read length prefixed utf-8 string
+func readString(data []byte) (string, []byte, error) {
+	if len(data) < 2 {
+		return "", nil, io.ErrUnexpectedEOF
+	}
+
+	length := int(binary.BigEndian.Uint16(data))
+
+	if len(data) < 2+length {
+		return "", nil, io.ErrUnexpectedEOF
+	}
+
+	return string(data[2 : 2+length]), data[2+length:], nil
+}
+
+// appendString - Will append length prefixed utf-8 string
+func appendString(data []byte, value string) []byte {
+	data = append(data, byte(len(value)>>8), byte(len(value)))
+	return append(data, value...)
+}
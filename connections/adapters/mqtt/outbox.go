@@ -0,0 +1,124 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"time"
+
+	"github.com/powerunit-io/platform/metrics"
+	"github.com/powerunit-io/platform/utils"
+
+	MQTT "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
+)
+
+// queuedPublish - Publish with TTL held by the connection while disconnected
+type queuedPublish struct {
+	topic    string
+	qos      byte
+	retained bool
+	payload  interface{}
+	deadline time.Time
+}
+
+// OnPublishExpired - Will register callback invoked with topic (without
+// topicPrefix) of each publish abandoned as its TTL expired, see PublishWithTTL
+func (c *Connection) OnPublishExpired(fn func(topic string)) {
+	c.outboxLock.Lock()
+	defer c.outboxLock.Unlock()
+
+	c.onExpired = fn
+}
+
+// hold - Will keep publish made while disconnected until reconnect. Once
+// `outboxSize` publishes are held, the oldest one is expired.
+func (c *Connection) hold(publish queuedPublish) {
+	c.outboxLock.Lock()
+	var dropped []queuedPublish
+
+	if len(c.outbox) >= c.GetOutboxSize() {
+		dropped, c.outbox = c.outbox[:1], c.outbox[1:]
+	}
+
+	c.outbox = append(c.outbox, publish)
+	c.outboxLock.Unlock()
+
+	for _, publish := range dropped {
+		c.expired(publish.topic)
+	}
+}
+
+// flushOutbox - Will publish held messages once connection is re-established,
+// abandoning ones whose TTL expired during the outage
+func (c *Connection) flushOutbox() {
+	c.outboxLock.Lock()
+	held := c.outbox
+	c.outbox = nil
+	c.outboxLock.Unlock()
+
+	if len(held) == 0 {
+		return
+	}
+
+	c.Info("Publishing (held: %d) mqtt (worker: %s) messages after reconnect ...", len(held), c.Name())
+
+	for _, publish := range held {
+		ttl := publish.deadline.Sub(time.Now())
+
+		if ttl <= 0 {
+			c.expired(publish.topic)
+			continue
+		}
+
+		c.publish(publish.topic, publish.qos, publish.retained, publish.payload, ttl)
+	}
+}
+
+// trackTTL - Will report publish, which broker did not acknowledge within ttl,
+// as expired. Paho keeps message it already sent in its store, so such publish
+// may still be delivered on reconnect; it's no longer tracked by Flush.
+func (c *Connection) trackTTL(topic string, token MQTT.Token, ttl time.Duration) {
+	go func() {
+		if token.WaitTimeout(ttl) {
+			return
+		}
+
+		c.pendingLock.Lock()
+		delete(c.pending, token)
+		c.pendingLock.Unlock()
+
+		c.expired(topic)
+	}()
+}
+
+// expired - Will count and report publish abandoned due to its TTL
+func (c *Connection) expired(topic string) {
+	metrics.Inc(PublishesExpiredMetric, c.metricLabels())
+	c.Warning("Abandoning mqtt (worker: %s) publish on (topic: %s) as its TTL expired", c.Name(), topic)
+
+	c.outboxLock.Lock()
+	fn := c.onExpired
+	c.outboxLock.Unlock()
+
+	if fn != nil {
+		fn(topic)
+	}
+}
+
+// GetPublishTTL - will return TTL applied to Publish, see PublishWithTTL.
+// Defaults to 0 (publishes never expire).
+func (c *Connection) GetPublishTTL() time.Duration {
+	return c.getDuration("publishTTL", 0)
+}
+
+// GetOutboxSize - will return how many publishes with TTL are held while
+// disconnected. Defaults to OutboxSize.
+func (c *Connection) GetOutboxSize() int {
+	if size, ok := utils.AsInt(c.connection()["outboxSize"]); ok && size > 0 {
+		return size
+	}
+
+	return OutboxSize
+}
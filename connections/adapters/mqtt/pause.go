@@ -0,0 +1,67 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+// Pause - Will halt event consumption (Consume, OnEvent, ConsumeSharded and
+// PipeTo) without disconnecting from the broker. Subscriptions stay in place so
+// received events keep buffering until buffer is full, after which broker
+// message handler blocks and messages are not acked until Resume. Consumers
+// ranging over DrainEvents own the channel and are not gated.
+func (c *Connection) Pause() {
+	c.pauseLock.Lock()
+	defer c.pauseLock.Unlock()
+
+	if c.resumed != nil {
+		return
+	}
+
+	c.Warning("Pausing event consumption for mqtt (worker: %s) ...", c.Name())
+	c.resumed = make(chan bool)
+}
+
+// Resume - Will continue event consumption halted by Pause
+func (c *Connection) Resume() {
+	c.pauseLock.Lock()
+	defer c.pauseLock.Unlock()
+
+	if c.resumed == nil {
+		return
+	}
+
+	c.Info("Resuming event consumption for mqtt (worker: %s) - (buffered: %d) ...", c.Name(), len(c.events))
+
+	close(c.resumed)
+	c.resumed = nil
+}
+
+// Paused - Will return whenever event consumption is paused
+func (c *Connection) Paused() bool {
+	c.pauseLock.Lock()
+	defer c.pauseLock.Unlock()
+
+	return c.resumed != nil
+}
+
+// waitResumed - Will block while consumption is paused. False is returned in
+// case that connection got stopped in the meantime.
+func (c *Connection) waitResumed() bool {
+	c.pauseLock.Lock()
+	resumed := c.resumed
+	c.pauseLock.Unlock()
+
+	if resumed == nil {
+		return true
+	}
+
+	select {
+	case <-resumed:
+		return true
+	case <-c.done:
+		return false
+	case <-c.stop:
+		return false
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+
+	MQTT "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
+)
+
+// payloadMessage - Wraps received message replacing its payload. Used when single
+// mqtt message carries multiple events.
+type payloadMessage struct {
+	MQTT.Message
+	payload []byte
+}
+
+// Payload -
+func (m *payloadMessage) Payload() []byte {
+	return m.payload
+}
+
+// topicMessage - Wraps received message replacing its topic. Used to hide topic
+// prefix from workers.
+type topicMessage struct {
+	MQTT.Message
+	topic string
+}
+
+// Topic -
+func (m *topicMessage) Topic() string {
+	return m.topic
+}
+
+// withTopic - Will wrap message replacing its topic (if it differs)
+func withTopic(msg MQTT.Message, topic string) MQTT.Message {
+	if msg.Topic() == topic {
+		return msg
+	}
+
+	return &topicMessage{Message: msg, topic: topic}
+}
+
+// splitLines - Will split newline delimited message into one message per line.
+// Blank lines are skipped.
+func splitLines(msg MQTT.Message) []MQTT.Message {
+	messages := []MQTT.Message{}
+
+	for _, line := range bytes.Split(msg.Payload(), []byte("\n")) {
+		line = bytes.TrimSpace(line)
+
+		if len(line) == 0 {
+			continue
+		}
+
+		messages = append(messages, &payloadMessage{Message: msg, payload: line})
+	}
+
+	return messages
+}
+
+// decodePayload - Will undo `payloadEncoding` transport encoding of the message
+// payload. Raw payloads are returned as they are.
+func (c *Connection) decodePayload(msg MQTT.Message) (MQTT.Message, error) {
+	if c.GetPayloadEncoding() != Base64PayloadEncoding {
+		return msg, nil
+	}
+
+	encoded := bytes.TrimSpace(msg.Payload())
+	payload := make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
+	n, err := base64.StdEncoding.Decode(payload, encoded)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not decode base64 payload on (topic: %s) due to (err: %s)", msg.Topic(), err)
+	}
+
+	return &payloadMessage{Message: msg, payload: payload[:n]}, nil
+}
+
+// GetPayloadEncoding - will return transport encoding of received payloads,
+// undone before decryption. Defaults to raw.
+func (c *Connection) GetPayloadEncoding() string {
+	if encoding, ok := c.connection()["payloadEncoding"].(string); ok {
+		return encoding
+	}
+
+	return RawPayloadEncoding
+}
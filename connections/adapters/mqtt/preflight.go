@@ -0,0 +1,36 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"fmt"
+
+	"github.com/powerunit-io/platform/config"
+	"github.com/powerunit-io/platform/logging"
+)
+
+// ValidateConfigs - Will validate each of the worker configs without starting
+// (or connecting) them and return error for every invalid one, annotated with
+// its index within cfgs. Empty result means that all configs are valid.
+// Intended for preflight checks of the whole configuration file.
+func ValidateConfigs(cfgs []*config.Config) []error {
+	errors := []error{}
+
+	for index, cfg := range cfgs {
+		if cfg == nil {
+			errors = append(errors, fmt.Errorf("Could not validate mqtt worker (index: %d) as config is missing", index))
+			continue
+		}
+
+		connection := &Connection{Logger: &logging.Logger{}, Config: cfg}
+
+		if err := connection.Validate(); err != nil {
+			errors = append(errors, fmt.Errorf("Could not validate mqtt worker (index: %d) - (worker: %s) due to (err: %s)", index, connection.Name(), err))
+		}
+	}
+
+	return errors
+}
@@ -0,0 +1,139 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"fmt"
+	"time"
+
+	MQTT "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
+)
+
+// Publish - Will queue message for delivery to the broker (topicPrefix is
+// prepended to the topic). Publish does not wait for delivery, use Flush in case
+// you need to ensure that message is delivered. With `publishTTL` configured it
+// behaves as PublishWithTTL.
+func (c *Connection) Publish(topic string, qos byte, retained bool, payload interface{}) error {
+	return c.PublishWithTTL(topic, qos, retained, payload, c.GetPublishTTL())
+}
+
+// PublishWithTTL - Will publish message which MUST NOT be delivered later than
+// ttl from now. Messages published while disconnected are held by connection
+// (up to `outboxSize`) instead of paho and published on reconnect unless their
+// TTL expired meanwhile. Expired publishes are counted and reported through
+// OnPublishExpired. Zero ttl means no expiration.
+func (c *Connection) PublishWithTTL(topic string, qos byte, retained bool, payload interface{}, ttl time.Duration) error {
+	if c.client() == nil {
+		c.Warning("Could not publish to (topic: %s) for (worker: %s) as connection is not started", topic, c.Name())
+		return ErrNotConnected
+	}
+
+	c.Debug("Publishing mqtt (worker: %s) message on (topic: %s) - (qos: %d)", c.Name(), topic, qos)
+
+	if c.encryptor != nil {
+		encrypted, err := c.encrypt(topic, payload)
+
+		if err != nil {
+			return err
+		}
+
+		payload = encrypted
+	}
+
+	if ttl > 0 && !c.connected() {
+		c.hold(queuedPublish{topic: topic, qos: qos, retained: retained, payload: payload, deadline: time.Now().Add(ttl)})
+		return nil
+	}
+
+	c.publish(topic, qos, retained, payload, ttl)
+	return nil
+}
+
+// publish - Will hand already encrypted message over to paho
+func (c *Connection) publish(topic string, qos byte, retained bool, payload interface{}, ttl time.Duration) {
+	token := c.client().Publish(c.PrefixTopic(topic), qos, retained, payload)
+	c.track(c.PrefixTopic(topic), token)
+
+	if ttl > 0 {
+		c.trackTTL(topic, token, ttl)
+	}
+}
+
+// encrypt - Will encrypt string or []byte payload (other payload types can't be
+// encrypted)
+func (c *Connection) encrypt(topic string, payload interface{}) ([]byte, error) {
+	var plaintext []byte
+
+	switch p := payload.(type) {
+	case []byte:
+		plaintext = p
+	case string:
+		plaintext = []byte(p)
+	default:
+		return nil, fmt.Errorf("Could not encrypt mqtt (worker: %s) payload of (type: %T), only string and []byte are supported", c.Name(), payload)
+	}
+
+	ciphertext, err := c.encryptor(topic, plaintext)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not encrypt mqtt (worker: %s) payload for (topic: %s) due to (err: %s)", c.Name(), topic, err)
+	}
+
+	return ciphertext, nil
+}
+
+// Flush - Will wait for all in-flight publishes to complete or for timeout
+// to expire, whichever comes first.
+func (c *Connection) Flush(timeout time.Duration) error {
+	c.pendingLock.Lock()
+	tokens := make([]MQTT.Token, 0, len(c.pending))
+	for token := range c.pending {
+		tokens = append(tokens, token)
+	}
+	c.pendingLock.Unlock()
+
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	c.Info("Flushing (pending: %d) mqtt publishes for (worker: %s) ...", len(tokens), c.Name())
+
+	deadline := time.Now().Add(timeout)
+
+	for i, token := range tokens {
+		if !token.WaitTimeout(deadline.Sub(time.Now())) {
+			return fmt.Errorf(
+				"Could not flush mqtt (worker: %s) as (pending: %d) publishes did not complete within (timeout: %s)",
+				c.Name(), len(tokens)-i, timeout,
+			)
+		}
+	}
+
+	return nil
+}
+
+// track - Will keep publish token as pending until it completes
+func (c *Connection) track(topic string, token MQTT.Token) {
+	c.pendingLock.Lock()
+	if c.pending == nil {
+		c.pending = make(map[MQTT.Token]bool)
+	}
+	c.pending[token] = true
+	c.pendingLock.Unlock()
+
+	go func() {
+		if token.Wait() && token.Error() != nil {
+			c.Error(
+				"Could not publish mqtt (worker: %s) message on (topic: %s) due to (err: %s)",
+				c.Name(), topic, token.Error(),
+			)
+		}
+
+		c.pendingLock.Lock()
+		delete(c.pending, token)
+		c.pendingLock.Unlock()
+	}()
+}
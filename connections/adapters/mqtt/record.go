@@ -0,0 +1,72 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"fmt"
+
+	"github.com/powerunit-io/platform/connections/adapters/file"
+
+	MQTT "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
+)
+
+// StartRecording - Will write each received message (topic without topicPrefix,
+// payload, timestamp and mqtt metadata) into capture at path, replayable by the
+// file adapter. Capture is rotated by `recordMaxSize` / `recordMaxAge` config.
+func (c *Connection) StartRecording(path string) error {
+	c.recorderLock.Lock()
+	defer c.recorderLock.Unlock()
+
+	if c.recorder != nil {
+		return fmt.Errorf("Could not start recording mqtt (worker: %s) into (file: %s) as it's already recording", c.Name(), path)
+	}
+
+	recorder, err := file.NewRecorder(path, c.GetRecordMaxSize(), c.GetRecordMaxAge())
+
+	if err != nil {
+		return err
+	}
+
+	recorder.SetSource(c.Name())
+
+	c.Info("Recording mqtt (worker: %s) messages into (file: %s) ...", c.Name(), path)
+	c.recorder = recorder
+
+	return nil
+}
+
+// StopRecording - Will stop recording and close the capture
+func (c *Connection) StopRecording() error {
+	c.recorderLock.Lock()
+	defer c.recorderLock.Unlock()
+
+	if c.recorder == nil {
+		return nil
+	}
+
+	err := c.recorder.Close()
+	c.recorder = nil
+
+	return err
+}
+
+// record - Will write message into capture, if recording
+func (c *Connection) record(msg MQTT.Message) {
+	c.recorderLock.Lock()
+	defer c.recorderLock.Unlock()
+
+	if c.recorder == nil {
+		return
+	}
+
+	metadata := map[string]interface{}{
+		"qos": msg.Qos(), "retained": msg.Retained(), "duplicate": msg.Duplicate(), "message_id": msg.MessageID(),
+	}
+
+	if err := c.recorder.Record(msg.Topic(), msg.Payload(), metadata); err != nil {
+		c.Error("Could not record mqtt (worker: %s) message due to (err: %s)", c.Name(), err)
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/powerunit-io/platform/events"
+	"github.com/powerunit-io/platform/metrics"
+	"github.com/powerunit-io/platform/utils"
+
+	MQTT "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
+)
+
+// responseRoute - Single broker subscription to response topic shared by all
+// requests waiting on it, keyed by correlation id
+type responseRoute struct {
+	waiters map[string]chan events.Event
+	ready   chan bool
+	err     error
+}
+
+// Request - Will publish request payload (with generated `correlation_id`) to
+// request topic and wait for event carrying the same correlation id on response
+// topic, or for context to be done. Concurrent requests share one subscription
+// per response topic, which is removed once the last of them completes unless
+// the topic is one of Subscriptions. Responses are decoded, decrypted and
+// validated the same way as messages handled by BrokerHandler, but are not
+// pushed to the events channel.
+func (c *Connection) Request(ctx context.Context, reqTopic string, payload map[string]interface{}, respTopic string) (events.Event, error) {
+	if !c.connected() {
+		return events.Event{}, ErrNotConnected
+	}
+
+	correlationID, err := newCorrelationID()
+
+	if err != nil {
+		return events.Event{}, err
+	}
+
+	request := make(map[string]interface{}, len(payload)+1)
+	for key, value := range payload {
+		request[key] = value
+	}
+	request["correlation_id"] = correlationID
+
+	body, err := json.Marshal(request)
+
+	if err != nil {
+		return events.Event{}, fmt.Errorf("Could not encode request for (topic: %s) due to (err: %s)", reqTopic, err)
+	}
+
+	responses := make(chan events.Event, 1)
+
+	if err := c.awaitResponse(respTopic, correlationID, responses); err != nil {
+		return events.Event{}, err
+	}
+
+	defer c.forgetResponse(respTopic, correlationID)
+
+	c.Debug("Sending request (correlation_id: %s) on (topic: %s) for (worker: %s)", correlationID, reqTopic, c.Name())
+
+	if err := c.Publish(reqTopic, c.GetBrokerQoS(), false, body); err != nil {
+		return events.Event{}, err
+	}
+
+	select {
+	case event := <-responses:
+		return event, nil
+	case <-ctx.Done():
+		return events.Event{}, fmt.Errorf(
+			"Did not receive response (correlation_id: %s) on (topic: %s) due to (err: %s)",
+			correlationID, respTopic, ctx.Err(),
+		)
+	}
+}
+
+// awaitResponse - Will register waiter for response carrying correlation id,
+// subscribing to response topic in case that no other request waits on it
+func (c *Connection) awaitResponse(topic string, correlationID string, responses chan events.Event) error {
+	c.responsesLock.Lock()
+
+	if c.responses == nil {
+		c.responses = make(map[string]*responseRoute)
+	}
+
+	route, subscribed := c.responses[topic]
+
+	if !subscribed {
+		route = &responseRoute{waiters: make(map[string]chan events.Event), ready: make(chan bool)}
+		c.responses[topic] = route
+	}
+
+	route.waiters[correlationID] = responses
+	c.responsesLock.Unlock()
+
+	if !subscribed {
+		route.err = c.subscribeResponses(topic)
+		close(route.ready)
+	}
+
+	<-route.ready
+
+	if route.err == nil {
+		return nil
+	}
+
+	c.responsesLock.Lock()
+	delete(route.waiters, correlationID)
+	if c.responses[topic] == route {
+		delete(c.responses, topic)
+	}
+	c.responsesLock.Unlock()
+
+	return route.err
+}
+
+// forgetResponse - Will remove waiter and, in case that it was the last one,
+// unsubscribe from response topic unless it's one of Subscriptions
+func (c *Connection) forgetResponse(topic string, correlationID string) {
+	c.responsesLock.Lock()
+	defer c.responsesLock.Unlock()
+
+	route := c.responses[topic]
+
+	if route == nil {
+		return
+	}
+
+	delete(route.waiters, correlationID)
+
+	if len(route.waiters) > 0 {
+		return
+	}
+
+	delete(c.responses, topic)
+
+	if utils.StringInSlice(topic, c.Subscriptions()) {
+		return
+	}
+
+	// Unsubscribe is issued under the lock so it always reaches the broker
+	// ahead of subscribe of the request which comes next
+	token := c.client().Unsubscribe(c.PrefixTopic(topic))
+
+	go func() {
+		if !token.WaitTimeout(c.GetSubscribeTimeout()) {
+			c.Error("Could not receive mqtt UNSUBACK for response (topic: %s) within (timeout: %s)", topic, c.GetSubscribeTimeout())
+			return
+		}
+
+		if token.Error() != nil {
+			c.Error("Could not unsubscribe from response (topic: %s) for (worker: %s) due to (err: %s)", topic, c.Name(), token.Error())
+		}
+	}()
+}
+
+// subscribeResponses - Will subscribe to response topic routing its messages
+// through dispatchResponse
+func (c *Connection) subscribeResponses(topic string) error {
+	token := c.client().Subscribe(c.PrefixTopic(topic), c.GetBrokerQoS(), c.dispatchResponse)
+
+	if !token.WaitTimeout(c.GetSubscribeTimeout()) {
+		return fmt.Errorf(
+			"Could not receive mqtt SUBACK for response (topic: %s) within (timeout: %s)",
+			topic, c.GetSubscribeTimeout(),
+		)
+	}
+
+	if token.Error() != nil {
+		return fmt.Errorf(
+			"Could not subscribe to response (topic: %s) for (worker: %s) due to (err: %s)",
+			topic, c.Name(), token.Error(),
+		)
+	}
+
+	return nil
+}
+
+// dispatchResponse - Will hand response over to the request waiting for its
+// correlation id. Client library routes matching messages only here, so the
+// ones no request waits for are handled by BrokerHandler in case that any of
+// Subscriptions matches them.
+func (c *Connection) dispatchResponse(client *MQTT.Client, raw MQTT.Message) {
+	topic := c.StripTopic(raw.Topic())
+	_, subscribed := c.matchingSubscription(topic)
+
+	msg, metric, err := c.unwrap(withTopic(raw, topic))
+
+	if err == nil {
+		if err = c.validate(msg); err != nil {
+			metric = InvalidMessagesMetric
+		}
+	}
+
+	if err == nil {
+		if event, err := events.NewEvent(msg); err == nil {
+			if c.respond(topic, event) {
+				return
+			}
+
+			event.Release()
+		}
+	}
+
+	if subscribed {
+		c.BrokerHandler(client, raw)
+		return
+	}
+
+	if err != nil {
+		metrics.Inc(metric, c.metricLabels())
+		c.Error("Dropping mqtt (worker: %s) response on (topic: %s) due to (err: %s)", c.Name(), topic, err)
+	}
+}
+
+// respond - Will pass event to the request waiting for its correlation id on
+// any response topic matching topic. Returns false if no request waits for it.
+func (c *Connection) respond(topic string, event events.Event) bool {
+	c.responsesLock.Lock()
+	defer c.responsesLock.Unlock()
+
+	for filter, route := range c.responses {
+		if _, ok := utils.MatchTopic(filter, topic); !ok {
+			continue
+		}
+
+		if responses, ok := route.waiters[event.CorrelationID]; ok {
+			select {
+			case responses <- event:
+			default:
+				event.Release()
+			}
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// newCorrelationID - Will generate random correlation id
+func newCorrelationID() (string, error) {
+	id := make([]byte, 16)
+
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("Could not generate correlation id due to (err: %s)", err)
+	}
+
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strconv"
+
+	"github.com/powerunit-io/platform/utils"
+)
+
+// Resolver - Pluggable broker host lookup used by `resolveBroker` (e.g. querying
+// specific dns server). Satisfied by *net.Resolver.
+type Resolver interface {
+	// LookupHost - Will return addresses of the host
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// SetResolver - Will replace resolver used to re-resolve broker host on each
+// connect attempt. Defaults to net.DefaultResolver. MUST be set before Start.
+func (c *Connection) SetResolver(resolver Resolver) {
+	c.resolver = resolver
+}
+
+// brokerServer - Will return broker uri to dial for the address picked for the
+// attempt. In case that `resolveBroker` is enabled host is resolved right away
+// and broker is dialed by its current ip, so reconnects follow dns failover
+// instead of the address resolved once. For `tls` network server name is pinned
+// to the host, even without any tls files configured, so certificate is still
+// verified against it.
+func (c *Connection) brokerServer(address string, tlsConfig *tls.Config) (string, *tls.Config) {
+	host, port, err := utils.ParseBrokerAddress(address, c.GetDefaultBrokerPort())
+
+	if err != nil || !c.GetResolveBroker() || net.ParseIP(host) != nil {
+		return c.brokerURI(address), tlsConfig
+	}
+
+	resolver := c.resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), BrokerResolveTimeout)
+	defer cancel()
+
+	ips, err := resolver.LookupHost(ctx, host)
+
+	if err != nil || len(ips) == 0 {
+		c.Warning("Could not resolve mqtt (worker: %s) broker (host: %s) due to (err: %v). Dialing by host ...", c.Name(), host, err)
+		return c.brokerURI(address), tlsConfig
+	}
+
+	c.brokerLock.Lock()
+	if c.resolved == nil {
+		c.resolved = make(map[string]string)
+	}
+
+	previous := c.resolved[host]
+	c.resolved[host] = ips[0]
+	c.brokerLock.Unlock()
+
+	if previous != "" && previous != ips[0] {
+		c.Warning("Mqtt (worker: %s) broker (host: %s) moved from (ip: %s) to (ip: %s)", c.Name(), host, previous, ips[0])
+	}
+
+	// Without server name certificate would be verified against the ip
+	if network, _ := utils.AsString(c.connection()["network"]); network == "tls" {
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: host}
+		} else if tlsConfig.ServerName == "" {
+			tlsConfig = tlsConfig.Clone()
+			tlsConfig.ServerName = host
+		}
+	}
+
+	return c.brokerURI(net.JoinHostPort(ips[0], strconv.Itoa(port))), tlsConfig
+}
+
+// GetResolveBroker - will return whenever broker host is re-resolved on each
+// connect attempt. Defaults to false.
+func (c *Connection) GetResolveBroker() bool {
+	enabled, _ := c.connection()["resolveBroker"].(bool)
+	return enabled
+}
@@ -0,0 +1,89 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"github.com/powerunit-io/platform/events"
+)
+
+// ConsumeSharded - Will start n goroutines invoking callback for received events.
+// Events are routed by hash of the key returned by keyFn (e.g. device id) so the
+// same key is always handled by the same goroutine, preserving per key ordering
+// while keys are processed in parallel. It's callback consumer, so
+// ErrConsumerRegistered is returned in case that events are already consumed.
+func (c *Connection) ConsumeSharded(n int, keyFn func(events.Event) string, fn func(events.Event)) error {
+	if n < 1 {
+		return fmt.Errorf("Could not consume mqtt (worker: %s) events sharded as (shards: %d) MUST be positive number", c.Name(), n)
+	}
+
+	if err := c.claim(CallbackConsumer); err != nil {
+		return err
+	}
+
+	c.Info("Starting (shards: %d) event processors for mqtt (worker: %s) ...", n, c.Name())
+
+	shards := make([]chan events.Event, n)
+
+	for i := range shards {
+		shards[i] = make(chan events.Event, ShardBufferSize)
+
+		c.routines.Add(1)
+
+		go func(shard chan events.Event) {
+			defer c.routines.Done()
+
+			for event := range shard {
+				fn(event)
+			}
+		}(shards[i])
+	}
+
+	c.routines.Add(1)
+	go c.dispatch(shards, keyFn)
+
+	return nil
+}
+
+// dispatch - Will route events to shards until connection is stopped. Shards
+// are closed on exit so their goroutines finish events already routed to them.
+func (c *Connection) dispatch(shards []chan events.Event, keyFn func(events.Event) string) {
+	defer c.routines.Done()
+
+	defer func() {
+		for _, shard := range shards {
+			close(shard)
+		}
+	}()
+
+	for {
+		select {
+		case event := <-c.events:
+			if !c.waitResumed() {
+				event.Release()
+				return
+			}
+
+			if !c.stale(event) {
+				shards[shardOf(keyFn(event), len(shards))] <- event
+			}
+		case <-c.done:
+			return
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// shardOf - Will return shard index of the key
+func shardOf(key string, n int) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+
+	return int(hash.Sum32() % uint32(n))
+}
@@ -0,0 +1,94 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"time"
+
+	"github.com/powerunit-io/platform/metrics"
+	"github.com/powerunit-io/platform/utils"
+)
+
+// slowConsumer - Tracks how long buffer depth stays above the watermark
+type slowConsumer struct {
+	since    time.Time
+	slow     bool
+	callback func(depth int, duration time.Duration)
+}
+
+// OnSlowConsumer - Will register callback invoked once buffer depth stays above
+// `slowConsumerWatermark` for longer than `slowConsumerDuration`, i.e. before
+// events start piling up behind full buffer. Callback fires once per slow
+// period; it's armed again once buffer depth drops below the watermark.
+func (c *Connection) OnSlowConsumer(fn func(depth int, duration time.Duration)) {
+	c.slowLock.Lock()
+	defer c.slowLock.Unlock()
+
+	c.slow.callback = fn
+}
+
+// checkSlowConsumer - Will observe buffer depth before event is enqueued and
+// fire slow consumer callback when needed
+func (c *Connection) checkSlowConsumer() {
+	depth, watermark := len(c.events), c.GetSlowConsumerWatermark()
+	now := time.Now()
+
+	c.slowLock.Lock()
+
+	if depth < watermark {
+		c.slow.since = time.Time{}
+		c.slow.slow = false
+		c.slowLock.Unlock()
+		return
+	}
+
+	if c.slow.since.IsZero() {
+		c.slow.since = now
+	}
+
+	duration, fire := now.Sub(c.slow.since), false
+
+	if !c.slow.slow && duration >= c.GetSlowConsumerDuration() {
+		c.slow.slow = true
+		fire = true
+	}
+
+	callback := c.slow.callback
+	c.slowLock.Unlock()
+
+	if !fire {
+		return
+	}
+
+	metrics.Inc(SlowConsumersMetric, c.metricLabels())
+	c.Error(
+		"Mqtt (worker: %s) consumer is slow as (buffered: %d) events stayed above (watermark: %d) for (duration: %s)",
+		c.Name(), depth, watermark, duration,
+	)
+
+	if callback != nil {
+		callback(depth, duration)
+	}
+}
+
+// GetSlowConsumerWatermark - will return buffer depth considered high. Defaults
+// to SlowConsumerWatermark fraction of the buffer size.
+func (c *Connection) GetSlowConsumerWatermark() int {
+	if watermark, ok := utils.AsInt(c.connection()["slowConsumerWatermark"]); ok && watermark > 0 {
+		return watermark
+	}
+
+	if watermark := int(float64(cap(c.events)) * SlowConsumerWatermark); watermark > 0 {
+		return watermark
+	}
+
+	return 1
+}
+
+// GetSlowConsumerDuration - will return how long buffer depth has to stay high
+func (c *Connection) GetSlowConsumerDuration() time.Duration {
+	return c.getDuration("slowConsumerDuration", SlowConsumerDuration)
+}
@@ -0,0 +1,96 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"time"
+
+	"github.com/powerunit-io/platform/events"
+)
+
+// initialSnapshot - Retained events collected after the first subscribe
+type initialSnapshot struct {
+	fn         func([]events.Event)
+	events     []events.Event
+	collecting bool
+	once       bool
+}
+
+// OnInitialSnapshot - Will register callback receiving, in single batch, retained
+// (last known state) events broker delivered within `snapshotWindow` after the
+// first subscribe. Such events are not pushed into the event stream; retained
+// events arriving later and all live events are streamed as usual. Callback is
+// invoked once, from internal goroutine, even when no retained event arrived.
+// It MUST be registered before Start.
+func (c *Connection) OnInitialSnapshot(fn func([]events.Event)) {
+	c.snapshotLock.Lock()
+	defer c.snapshotLock.Unlock()
+
+	c.snapshot.fn = fn
+}
+
+// startSnapshot - Will start collecting retained events on the first connect,
+// in case that snapshot callback is registered. It's invoked right before
+// subscribing so retained events delivered along with SUBACK are not missed.
+func (c *Connection) startSnapshot() {
+	c.snapshotLock.Lock()
+	defer c.snapshotLock.Unlock()
+
+	if c.snapshot.fn == nil || c.snapshot.once {
+		return
+	}
+
+	c.snapshot.once = true
+	c.snapshot.collecting = true
+
+	c.Info("Collecting initial snapshot for mqtt (worker: %s) for (window: %s) ...", c.Name(), c.GetSnapshotWindow())
+	time.AfterFunc(c.GetSnapshotWindow(), c.flushSnapshot)
+}
+
+// collectSnapshot - Will take retained event into initial snapshot. False is
+// returned in case that snapshot is not being collected.
+func (c *Connection) collectSnapshot(event events.Event) bool {
+	if !event.Retained {
+		return false
+	}
+
+	c.snapshotLock.Lock()
+	defer c.snapshotLock.Unlock()
+
+	if !c.snapshot.collecting {
+		return false
+	}
+
+	c.snapshot.events = append(c.snapshot.events, event)
+	return true
+}
+
+// flushSnapshot - Will end the window and hand collected events over to the
+// callback. Events are released in case that connection got stopped meanwhile.
+func (c *Connection) flushSnapshot() {
+	c.snapshotLock.Lock()
+	batch, fn := c.snapshot.events, c.snapshot.fn
+	c.snapshot.events = nil
+	c.snapshot.collecting = false
+	c.snapshotLock.Unlock()
+
+	if c.stopping() {
+		for _, event := range batch {
+			event.Release()
+		}
+
+		return
+	}
+
+	c.Info("Initial snapshot for mqtt (worker: %s) collected (events: %d)", c.Name(), len(batch))
+	fn(batch)
+}
+
+// GetSnapshotWindow - will return how long retained events are collected into
+// initial snapshot after the first subscribe
+func (c *Connection) GetSnapshotWindow() time.Duration {
+	return c.getDuration("snapshotWindow", SnapshotWindow)
+}
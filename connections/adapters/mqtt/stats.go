@@ -0,0 +1,112 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"sort"
+	"time"
+
+	"github.com/powerunit-io/platform/utils"
+)
+
+// SubscriptionStat - Activity of single subscription, see SubscriptionStats
+type SubscriptionStat struct {
+	Topic        string    `json:"topic"`
+	RequestedQoS byte      `json:"requested_qos"`
+	GrantedQoS   byte      `json:"granted_qos"`
+	Granted      bool      `json:"granted"`
+	Messages     uint64    `json:"messages"`
+	LastMessage  time.Time `json:"last_message"`
+}
+
+// topicStat - Message counters of single subscription topic filter
+type topicStat struct {
+	messages    uint64
+	lastMessage time.Time
+}
+
+// SubscriptionStats - Will return activity of each subscription (topics without
+// topicPrefix), sorted by topic. Message overlapping several subscriptions
+// (wildcards) is counted within each of them. Granted is false for
+// subscriptions which were not acknowledged by the broker (yet).
+func (c *Connection) SubscriptionStats() []SubscriptionStat {
+	c.grantedLock.Lock()
+	defer c.grantedLock.Unlock()
+
+	stats := []SubscriptionStat{}
+
+	for _, filter := range c.subscriptionFilters() {
+		stat := SubscriptionStat{Topic: c.StripTopic(filter), RequestedQoS: c.GetBrokerQoS()}
+
+		if requested, ok := c.requested[filter]; ok {
+			stat.RequestedQoS = requested
+		}
+
+		stat.GrantedQoS, stat.Granted = c.granted[filter]
+
+		if counter, ok := c.topicStats[filter]; ok {
+			stat.Messages, stat.LastMessage = counter.messages, counter.lastMessage
+		}
+
+		stats = append(stats, stat)
+	}
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Topic < stats[j].Topic })
+	return stats
+}
+
+// countSubscriptions - Will count message received on the broker topic within
+// each subscription it matched
+func (c *Connection) countSubscriptions(topic string) {
+	c.grantedLock.Lock()
+	defer c.grantedLock.Unlock()
+
+	if c.topicStats == nil {
+		c.topicStats = make(map[string]*topicStat)
+	}
+
+	for _, filter := range c.subscriptionFilters() {
+		if _, ok := utils.MatchTopic(filter, topic); !ok {
+			continue
+		}
+
+		counter, ok := c.topicStats[filter]
+
+		if !ok {
+			counter = &topicStat{}
+			c.topicStats[filter] = counter
+		}
+
+		counter.messages++
+		counter.lastMessage = time.Now()
+	}
+}
+
+// subscriptionFilters - Will return broker topic filters (with topicPrefix) of
+// configured subscriptions and of ones subscribed directly. MUST be invoked
+// with grantedLock held.
+func (c *Connection) subscriptionFilters() []string {
+	filters := []string{}
+	seen := map[string]bool{}
+
+	for _, topic := range c.Subscriptions() {
+		filter := c.PrefixTopic(topic)
+
+		if !seen[filter] {
+			seen[filter] = true
+			filters = append(filters, filter)
+		}
+	}
+
+	for filter := range c.granted {
+		if !seen[filter] {
+			seen[filter] = true
+			filters = append(filters, filter)
+		}
+	}
+
+	return filters
+}
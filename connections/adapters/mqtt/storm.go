@@ -0,0 +1,79 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"time"
+
+	"github.com/powerunit-io/platform/metrics"
+	"github.com/powerunit-io/platform/utils"
+)
+
+// reconnectStorm - Sliding window of reconnects
+type reconnectStorm struct {
+	reconnects []time.Time
+	raging     bool
+	callback   func(count int, window time.Duration)
+}
+
+// OnReconnectStorm - Will register callback invoked once number of reconnects
+// (lost connections and failed connect attempts) within `reconnectStormWindow`
+// reaches `reconnectStormThreshold`. Callback fires once per storm; it's armed
+// again once reconnect rate drops below the threshold.
+func (c *Connection) OnReconnectStorm(fn func(count int, window time.Duration)) {
+	c.stormLock.Lock()
+	defer c.stormLock.Unlock()
+
+	c.storm.callback = fn
+}
+
+// countReconnect - Will record reconnect and fire storm callback when needed
+func (c *Connection) countReconnect() {
+	metrics.Inc(ReconnectsMetric, c.metricLabels())
+
+	threshold, window := c.GetReconnectStormThreshold(), c.GetReconnectStormWindow()
+	now := time.Now()
+
+	c.stormLock.Lock()
+
+	reconnects := append(c.storm.reconnects, now)
+	for len(reconnects) > 0 && now.Sub(reconnects[0]) > window {
+		reconnects = reconnects[1:]
+	}
+	c.storm.reconnects = reconnects
+
+	count, fire := len(reconnects), false
+
+	if count < threshold {
+		c.storm.raging = false
+	} else if !c.storm.raging {
+		c.storm.raging = true
+		fire = c.storm.callback != nil
+	}
+
+	callback := c.storm.callback
+	c.stormLock.Unlock()
+
+	if fire {
+		c.Error("Mqtt (worker: %s) is in reconnect storm (reconnects: %d) within (window: %s)", c.Name(), count, window)
+		callback(count, window)
+	}
+}
+
+// GetReconnectStormThreshold - will return number of reconnects within window
+// considered a storm
+func (c *Connection) GetReconnectStormThreshold() int {
+	if threshold, ok := utils.AsInt(c.connection()["reconnectStormThreshold"]); ok && threshold > 0 {
+		return threshold
+	}
+
+	return ReconnectStormThreshold
+}
+
+// GetReconnectStormWindow - will return sliding window reconnects are counted in
+func (c *Connection) GetReconnectStormWindow() time.Duration {
+	return c.getDuration("reconnectStormWindow", ReconnectStormWindow)
+}
@@ -0,0 +1,302 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/powerunit-io/platform/utils"
+
+	MQTT "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
+)
+
+// Subscribe - Will subscribe to the topic (topicPrefix is prepended) retrying up
+// to maxRetryAttempts times with exponential backoff (see SubscribeRetryOptions).
+// Returns ErrNotConnected in case that connection is not established (yet) and
+// ErrSubscriptionRejected in case that broker refused subscription (SUBACK
+// failure). Rejected subscriptions are not retried, while SUBACK not received
+// within `subscribeTimeout` is.
+func (c *Connection) Subscribe(topic string, maxRetryAttempts int) error {
+	if !c.connected() {
+		c.Warning("Could not subscribe to (topic: %s) for (worker: %s) as connection is not established", topic, c.Name())
+		return ErrNotConnected
+	}
+
+	opts := SubscribeRetryOptions
+	opts.MaxAttempts = maxRetryAttempts + 1
+	attempt := 0
+
+	err := utils.Retry(context.Background(), opts, func() error {
+		c.Info(
+			"About to attempt subscribe to mqtt (topic: %s) for (worker: %s) -> (retry_attempt: %d)",
+			topic, c.Name(), attempt,
+		)
+		attempt++
+
+		_, err := c.subscribe(c.PrefixTopic(topic), c.GetBrokerQoS())
+
+		if err == ErrSubscriptionRejected {
+			c.Error(
+				"Broker rejected subscription to (topic: %s) for (worker: %s). Check broker ACLs.",
+				topic, c.Name(),
+			)
+			return utils.Permanent(err)
+		}
+
+		if err != nil {
+			c.Error("Could not subscribe to (topic: %s) for (worker: %s) due to (err: %s). Retrying ...", topic, c.Name(), err)
+		}
+
+		return err
+	})
+
+	if err == nil {
+		c.Info("Successfully subscribed (worker: %s) on (topic: %s)!", c.Name(), topic)
+	}
+
+	return err
+}
+
+// AddSubscription - Will subscribe to the topic at runtime (e.g. once discovered)
+// and remember it so it's subscribed again on each reconnect. In case that
+// connection is not established yet, topic is subscribed once it is.
+func (c *Connection) AddSubscription(topic string) error {
+	c.subscriptionsLock.Lock()
+	for _, subscription := range c.subscriptions {
+		if subscription == topic {
+			c.subscriptionsLock.Unlock()
+			return nil
+		}
+	}
+	c.subscriptions = append(c.subscriptions, topic)
+	c.subscriptionsLock.Unlock()
+
+	if !c.connected() || !c.leading() {
+		c.Info("Mqtt (worker: %s) will subscribe to (topic: %s) once connected as leader", c.Name(), topic)
+		return nil
+	}
+
+	return c.Subscribe(topic, MaxTopicSubscribeAttempts)
+}
+
+// Subscriptions - Will return all topics (without topicPrefix) connection is
+// subscribed to: configured `topic` and `topics` (unless deferSubscribe) and
+// ones added through AddSubscription
+func (c *Connection) Subscriptions() []string {
+	topics := []string{}
+
+	if !c.GetDeferSubscribe() {
+		if topic := c.GetTopic(); topic != "" {
+			topics = append(topics, topic)
+		}
+
+		topics = append(topics, c.GetTopics()...)
+	}
+
+	c.subscriptionsLock.Lock()
+	defer c.subscriptionsLock.Unlock()
+
+	return append(topics, c.subscriptions...)
+}
+
+// brokerSubscriptions - Will return subscriptions as known to the broker
+func (c *Connection) brokerSubscriptions() []string {
+	topics := c.Subscriptions()
+
+	for i, topic := range topics {
+		topics[i] = c.PrefixTopic(topic)
+	}
+
+	return topics
+}
+
+// subscribeAll - Will subscribe to all subscriptions, returning first rejection
+// (or last error) after attempting all of them. Non leader does not subscribe.
+func (c *Connection) subscribeAll() error {
+	if !c.Leader() {
+		c.Info("Mqtt (worker: %s) is not the leader. Staying connected but idle ...", c.Name())
+		c.setLeading(false)
+		return nil
+	}
+
+	c.setLeading(true)
+
+	var result error
+
+	topics, size := c.Subscriptions(), c.GetMaxTopicsPerSubscribe()
+
+	for start := 0; start < len(topics); start += size {
+		end := start + size
+
+		if end > len(topics) {
+			end = len(topics)
+		}
+
+		if err := c.subscribeBatch(topics[start:end], MaxTopicSubscribeAttempts); err != nil && result != ErrSubscriptionRejected {
+			result = err
+		}
+	}
+
+	return result
+}
+
+// subscribeBatch - Will subscribe to the topics with single SUBSCRIBE packet,
+// retrying the same way Subscribe does. Topics not rejected by the broker stay
+// subscribed even when some of them were rejected.
+func (c *Connection) subscribeBatch(topics []string, maxRetryAttempts int) error {
+	if len(topics) == 1 {
+		return c.Subscribe(topics[0], maxRetryAttempts)
+	}
+
+	if !c.connected() {
+		return ErrNotConnected
+	}
+
+	filters := make(map[string]byte, len(topics))
+
+	for _, topic := range topics {
+		filters[c.PrefixTopic(topic)] = c.GetBrokerQoS()
+	}
+
+	opts := SubscribeRetryOptions
+	opts.MaxAttempts = maxRetryAttempts + 1
+
+	err := utils.Retry(context.Background(), opts, func() error {
+		err := c.subscribeMultiple(filters)
+
+		if err == ErrSubscriptionRejected {
+			return utils.Permanent(err)
+		}
+
+		if err != nil {
+			c.Error("Could not subscribe to (topics: %d) for (worker: %s) due to (err: %s). Retrying ...", len(topics), c.Name(), err)
+		}
+
+		return err
+	})
+
+	if err == nil {
+		c.Info("Successfully subscribed (worker: %s) on (topics: %d)!", c.Name(), len(topics))
+	}
+
+	return err
+}
+
+// GrantedQoS - Will return qos granted by the broker for the topic. Second value
+// is false in case that topic was never subscribed.
+func (c *Connection) GrantedQoS(topic string) (byte, bool) {
+	c.grantedLock.Lock()
+	defer c.grantedLock.Unlock()
+
+	qos, ok := c.granted[c.PrefixTopic(topic)]
+	return qos, ok
+}
+
+// SubscribeWithResult - Will make single attempt to subscribe to the topic
+// (topicPrefix is prepended) with requested qos and return qos granted by the
+// broker SUBACK, which may be lower than requested (SubscribeFailure together
+// with ErrSubscriptionRejected in case of refusal). Unlike AddSubscription,
+// topic is not re-subscribed on reconnect.
+func (c *Connection) SubscribeWithResult(topic string, qos byte) (byte, error) {
+	if !c.connected() {
+		return SubscribeFailure, ErrNotConnected
+	}
+
+	return c.subscribe(c.PrefixTopic(topic), qos)
+}
+
+// subscribe - Will make single subscribe attempt and record granted qos
+func (c *Connection) subscribe(topic string, qos byte) (byte, error) {
+	token := c.client().Subscribe(topic, qos, nil)
+
+	if !token.WaitTimeout(c.GetSubscribeTimeout()) {
+		return SubscribeFailure, fmt.Errorf(
+			"Could not receive mqtt SUBACK for (topic: %s) within (timeout: %s)",
+			topic, c.GetSubscribeTimeout(),
+		)
+	}
+
+	if token.Error() != nil {
+		return SubscribeFailure, token.Error()
+	}
+
+	granted := qos
+
+	if st, ok := token.(*MQTT.SubscribeToken); ok {
+		if result, ok := st.Result()[topic]; ok {
+			granted = result
+		}
+	}
+
+	return granted, c.grant(topic, qos, granted)
+}
+
+// subscribeMultiple - Will make single subscribe attempt for all the filters.
+// ErrSubscriptionRejected is returned in case that any of them was rejected.
+func (c *Connection) subscribeMultiple(filters map[string]byte) error {
+	token := c.client().SubscribeMultiple(filters, nil)
+
+	if !token.WaitTimeout(c.GetSubscribeTimeout()) {
+		return fmt.Errorf(
+			"Could not receive mqtt SUBACK for (topics: %d) within (timeout: %s)",
+			len(filters), c.GetSubscribeTimeout(),
+		)
+	}
+
+	if token.Error() != nil {
+		return token.Error()
+	}
+
+	results := map[string]byte{}
+
+	if st, ok := token.(*MQTT.SubscribeToken); ok {
+		results = st.Result()
+	}
+
+	var result error
+
+	for topic, qos := range filters {
+		granted, ok := results[topic]
+
+		if !ok {
+			granted = qos
+		}
+
+		if err := c.grant(topic, qos, granted); err != nil {
+			c.Error("Broker rejected subscription to (topic: %s) for (worker: %s). Check broker ACLs.", topic, c.Name())
+			result = err
+		}
+	}
+
+	return result
+}
+
+// grant - Will record qos granted by the broker for the topic. QoS downgraded
+// by the broker (ACL, policy) is logged.
+func (c *Connection) grant(topic string, qos, granted byte) error {
+	c.grantedLock.Lock()
+	if c.granted == nil {
+		c.granted = make(map[string]byte)
+		c.requested = make(map[string]byte)
+	}
+	c.granted[topic] = granted
+	c.requested[topic] = qos
+	c.grantedLock.Unlock()
+
+	if granted == SubscribeFailure {
+		return ErrSubscriptionRejected
+	}
+
+	if granted < qos {
+		c.Warning(
+			"Broker downgraded mqtt (worker: %s) subscription to (topic: %s) - (requested_qos: %d) - (granted_qos: %d)",
+			c.Name(), topic, qos, granted,
+		)
+	}
+
+	return nil
+}
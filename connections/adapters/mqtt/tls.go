@@ -0,0 +1,107 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/powerunit-io/platform/utils"
+)
+
+// GetTLSConfig - will return tls configuration built from `tlsCert`, `tlsKey`
+// and `tlsCA` connection config. Nil is returned when none of them is set.
+//
+// Client certificate is not loaded once but read from disk on every handshake,
+// so rotated certificate files are picked up on the next reconnect.
+func (c *Connection) GetTLSConfig() (*tls.Config, error) {
+	connection := c.connection()
+	cert, _ := utils.AsString(connection["tlsCert"])
+	key, _ := utils.AsString(connection["tlsKey"])
+	ca, _ := utils.AsString(connection["tlsCA"])
+
+	if cert == "" && key == "" && ca == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{}
+
+	if ca != "" {
+		pem, err := ioutil.ReadFile(ca)
+
+		if err != nil {
+			return nil, fmt.Errorf(
+				"Could not read mqtt (worker: %s) tls ca (file: %s) due to (err: %s)",
+				c.Name(), ca, err,
+			)
+		}
+
+		config.RootCAs = x509.NewCertPool()
+
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf(
+				"Could not parse mqtt (worker: %s) tls ca (file: %s) as it contains no pem certificates",
+				c.Name(), ca,
+			)
+		}
+	}
+
+	if cert != "" {
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return c.loadClientCertificate(cert, key)
+		}
+	}
+
+	return config, nil
+}
+
+// ValidateTLS - will ensure that tls files are set in pairs and are loadable
+func (c *Connection) ValidateTLS(data map[string]interface{}) error {
+	for _, key := range []string{"tlsCert", "tlsKey", "tlsCA"} {
+		if value, ok := data[key]; ok {
+			if _, ok := value.(string); !ok {
+				return fmt.Errorf(
+					"Could not validate mqtt worker as connection %s is not string. (value: %v)",
+					key, value,
+				)
+			}
+		}
+	}
+
+	cert, _ := utils.AsString(data["tlsCert"])
+	key, _ := utils.AsString(data["tlsKey"])
+
+	if (cert == "") != (key == "") {
+		return fmt.Errorf(
+			"Could not validate mqtt worker as connection tlsCert and tlsKey MUST be set together. (tls_cert: %s) - (tls_key: %s)",
+			cert, key,
+		)
+	}
+
+	if cert != "" {
+		if _, err := c.loadClientCertificate(cert, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadClientCertificate - will read client certificate pair from disk
+func (c *Connection) loadClientCertificate(cert, key string) (*tls.Certificate, error) {
+	pair, err := tls.LoadX509KeyPair(cert, key)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Could not load mqtt (worker: %s) tls client certificate (cert: %s) - (key: %s) due to (err: %s)",
+			c.Name(), cert, key, err,
+		)
+	}
+
+	return &pair, nil
+}
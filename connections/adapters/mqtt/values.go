@@ -0,0 +1,34 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqtt ...
+package mqtt
+
+import "github.com/powerunit-io/platform/events"
+
+// WithValue - Will store connection-wide context value (tenant, environment,
+// ...) carried by every event emitted afterwards, see events.Event.ConnValue.
+// Values are copied on write so events already emitted keep the context they
+// were built with.
+func (c *Connection) WithValue(key string, value interface{}) {
+	c.valuesLock.Lock()
+	defer c.valuesLock.Unlock()
+
+	values := make(events.ConnValues, len(c.values)+1)
+
+	for k, v := range c.values {
+		values[k] = v
+	}
+
+	values[key] = value
+	c.values = values
+}
+
+// connValues - Will return current connection context
+func (c *Connection) connValues() events.ConnValues {
+	c.valuesLock.Lock()
+	defer c.valuesLock.Unlock()
+
+	return c.values
+}
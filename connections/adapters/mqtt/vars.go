@@ -5,16 +5,257 @@
 // Package mqtt ...
 package mqtt
 
+import (
+	"errors"
+	"time"
+
+	"github.com/powerunit-io/platform/events"
+	"github.com/powerunit-io/platform/utils"
+)
+
+const (
+	// Kind - Kind of the service reported to the managers
+	Kind = "mqtt"
+
+	// SubscribeFailure - Granted qos returned by broker when subscription is rejected
+	SubscribeFailure byte = 0x80
+
+	// EventsReceivedMetric - Name of the received messages counter
+	EventsReceivedMetric = "events_received"
+
+	// JSONPayloadFormat - Each message carries single json encoded event
+	JSONPayloadFormat = "json"
+
+	// NDJSONPayloadFormat - Each message carries newline delimited json events
+	NDJSONPayloadFormat = "ndjson"
+
+	// RawPayloadEncoding - Payloads are received as they are
+	RawPayloadEncoding = "raw"
+
+	// Base64PayloadEncoding - Payloads are base64 (standard, padded) encoded
+	Base64PayloadEncoding = "base64"
+
+	// StrictOrdering - Events are processed one by one in order they were received
+	StrictOrdering = "strict"
+
+	// ParallelOrdering - Events are processed concurrently by processor pool
+	ParallelOrdering = "parallel"
+
+	// FlushDelayed - Delayed events are pushed to dead letters on Stop
+	FlushDelayed = "flush"
+
+	// DiscardDelayed - Delayed events are dropped on Stop
+	DiscardDelayed = "discard"
+
+	// RawDeadLetterCodec - Dead letters are republished with original payload
+	RawDeadLetterCodec = "raw"
+
+	// DeadLettersMetric - Name of the failed events counter
+	DeadLettersMetric = "dead_letters"
+
+	// BrokerLogPrefix - Prefix of forwarded paho log lines
+	BrokerLogPrefix = "[paho] "
+
+	// ChannelConsumer - Events are consumed through DrainEvents / Consume
+	ChannelConsumer = "channel"
+
+	// CallbackConsumer - Events are consumed through OnEvent callback
+	CallbackConsumer = "callback"
+
+	// PipeConsumer - Events are pushed into caller channel through PipeTo
+	PipeConsumer = "pipe"
+
+	// DecryptFailuresMetric - Name of the counter of messages which failed decryption
+	DecryptFailuresMetric = "events_decrypt_failed"
+
+	// DecodeFailuresMetric - Name of the counter of events which failed decoding
+	// into registered type
+	DecodeFailuresMetric = "events_decode_failed"
+
+	// ReconnectsMetric - Name of the counter of lost connections and failed
+	// connect attempts
+	ReconnectsMetric = "reconnects"
+
+	// SlowConsumersMetric - Name of the counter of detected slow consumer periods
+	SlowConsumersMetric = "slow_consumers"
+
+	// InvalidMessagesMetric - Name of the counter of messages rejected by validator
+	InvalidMessagesMetric = "events_invalid"
+
+	// PayloadDecodeFailuresMetric - Name of the counter of messages dropped as
+	// their payload is not valid `payloadEncoding`
+	PayloadDecodeFailuresMetric = "payloads_decode_failed"
+
+	// DeniedMessagesMetric - Name of the counter of messages dropped as their
+	// topic is not allowed (see `allowTopics` and `denyTopics`)
+	DeniedMessagesMetric = "events_denied"
+
+	// StaleEventsMetric - Name of the counter of events dropped as older than
+	// `maxEventAge` by the time they were consumed
+	StaleEventsMetric = "events_stale"
+
+	// PublishesExpiredMetric - Name of the counter of publishes abandoned as
+	// their TTL expired
+	PublishesExpiredMetric = "publishes_expired"
+
+	// MemoryStore - Store config value for keeping in-flight messages in memory
+	MemoryStore = "memory"
+)
+
 var (
+	// ErrNotConnected - Returned when operation requires established connection
+	ErrNotConnected = errors.New("mqtt connection is not established")
+
+	// ErrSubscriptionRejected - Returned when broker refuses subscription
+	ErrSubscriptionRejected = errors.New("mqtt subscription rejected by the broker")
+
+	// ErrNotAuthorized - Returned when broker refuses connection credentials
+	ErrNotAuthorized = errors.New("mqtt broker refused connection as not authorized")
+
+	// ErrBrokerUnreachable - Returned when maxConnectAttempts (or
+	// maxReconnectAttempts) are exhausted
+	ErrBrokerUnreachable = errors.New("mqtt broker is unreachable")
+
+	// ErrDelayQueueFull - Returned by DelayEvent when `delayQueueSize` events
+	// are already delayed
+	ErrDelayQueueFull = errors.New("mqtt delay queue is full")
+
+	// ErrConsumerRegistered - Returned when events are consumed both by channel
+	// and by OnEvent callback
+	ErrConsumerRegistered = errors.New("mqtt events consumer is already registered")
+
 	// AvailableConnectionTypes -
 	AvailableConnectionTypes = []string{"tcp", "tls", "ws"}
 
-	// InitialConnectionTimeout -
-	InitialConnectionTimeout = 10
+	// DefaultBrokerPorts - Ports used when connection address has none
+	DefaultBrokerPorts = map[string]int{"tcp": 1883, "tls": 8883, "ws": 80}
+
+	// TopicConfigKeys - Worker specific connection keys, validated by ValidateTopic
+	TopicConfigKeys = []string{"topic", "topics", "deferSubscribe", "units", "maxTopicsPerSubscribe", "allowTopics", "denyTopics"}
+
+	// AvailablePayloadFormats -
+	AvailablePayloadFormats = []string{JSONPayloadFormat, NDJSONPayloadFormat}
+
+	// AvailablePayloadEncodings -
+	AvailablePayloadEncodings = []string{RawPayloadEncoding, Base64PayloadEncoding}
+
+	// AvailableOrderings -
+	AvailableOrderings = []string{StrictOrdering, ParallelOrdering}
+
+	// AvailableDelayOnStop -
+	AvailableDelayOnStop = []string{FlushDelayed, DiscardDelayed}
+
+	// AvailableDeadLetterCodecs -
+	AvailableDeadLetterCodecs = []string{RawDeadLetterCodec, events.JSONCodecName, events.BinaryCodecName}
+
+	// NotAuthorizedReasons - Lower cased fragments of connect refusal errors after
+	// which reconnecting is pointless
+	NotAuthorizedReasons = []string{"not authorized", "not authorised", "bad user name or password"}
+
+	// ServerUnavailableReasons - Lower cased fragments of connect refusal (CONNACK
+	// return code 3) and disconnect errors after which reconnecting is delayed by
+	// BusyReconnectInterval
+	ServerUnavailableReasons = []string{"server unavailable", "server busy"}
+
+	// InitialConnectionTimeout - Overridable by `connectTimeout` config
+	InitialConnectionTimeout = 10 * time.Second
+
+	// ReconnectInterval - How long to wait before attempting to connect again.
+	// Overridable by `reconnectInterval` config
+	ReconnectInterval = 2 * time.Second
+
+	// BusyReconnectInterval - How long to wait before attempting to connect again
+	// once broker reported it's unavailable. Overridable by `busyReconnectInterval`
+	// config
+	BusyReconnectInterval = 30 * time.Second
+
+	// ReconnectJitter - Fraction of ReconnectInterval reconnect delay is randomly
+	// shifted by (0.5 = ±50%). Disabled by `reconnectJitter: false` config
+	ReconnectJitter = 0.5
+
+	// SubscribeRetryOptions - Backoff between subscribe attempts. MaxAttempts is
+	// set by Subscribe caller
+	SubscribeRetryOptions = utils.RetryOptions{
+		BaseInterval: 500 * time.Millisecond,
+		MaxInterval:  5 * time.Second,
+		Jitter:       0.2,
+	}
+
+	// MaxTopicsPerSubscribe - How many topic filters are sent within single
+	// SUBSCRIBE. Overridable by `maxTopicsPerSubscribe` config
+	MaxTopicsPerSubscribe = 100
+
+	// MaxTopicsWarning - Number of configured topics Validate warns about
+	MaxTopicsWarning = 1000
 
 	// MaxTopicSubscribeAttempts -
 	MaxTopicSubscribeAttempts = 5
 
-	// GracefulShutdownTimeout -
-	GracefulShutdownTimeout = 1
+	// OutboxSize - How many publishes with TTL are held while disconnected.
+	// Overridable by `outboxSize` config
+	OutboxSize = 1000
+
+	// BrokerResolveTimeout - How long broker host lookup may take when
+	// `resolveBroker` is enabled before host is dialed as it is
+	BrokerResolveTimeout = 5 * time.Second
+
+	// DefaultBrokerWeight - Weight of brokers configured without one
+	DefaultBrokerWeight = 1
+
+	// DefaultDeadLetterSize - How many failed events are kept by default
+	DefaultDeadLetterSize = 100
+
+	// LeaderCheckInterval - How often elector is asked whenever leadership changed
+	LeaderCheckInterval = 2 * time.Second
+
+	// DelayQueueSize - How many events may be delayed at once. Overridable by
+	// `delayQueueSize` config
+	DelayQueueSize = 1000
+
+	// ShardBufferSize - Size of the per shard event channel used by ConsumeSharded
+	ShardBufferSize = 64
+
+	// DrainPollInterval - How often Drain checks whenever events are consumed
+	DrainPollInterval = 50 * time.Millisecond
+
+	// RecordMaxSize - Size (bytes) recording capture is rotated at. Overridable
+	// by `recordMaxSize` config, 0 disables size rotation
+	RecordMaxSize int64 = 64 << 20
+
+	// RecordMaxAge - Age recording capture is rotated at. Overridable by
+	// `recordMaxAge` config
+	RecordMaxAge = time.Hour
+
+	// ReconnectStormThreshold - Number of reconnects within ReconnectStormWindow
+	// considered a storm. Overridable by `reconnectStormThreshold` config
+	ReconnectStormThreshold = 10
+
+	// ReconnectStormWindow - Sliding window reconnects are counted in.
+	// Overridable by `reconnectStormWindow` config
+	ReconnectStormWindow = 5 * time.Minute
+
+	// SlowConsumerWatermark - Fraction of the buffer size buffer depth has to stay
+	// above to consider consumer slow. Overridable by `slowConsumerWatermark`
+	// config (number of events)
+	SlowConsumerWatermark = 0.8
+
+	// SnapshotWindow - How long retained events are collected into initial
+	// snapshot after the first subscribe. Overridable by `snapshotWindow` config
+	SnapshotWindow = 2 * time.Second
+
+	// SlowConsumerDuration - How long buffer depth has to stay above watermark.
+	// Overridable by `slowConsumerDuration` config
+	SlowConsumerDuration = 30 * time.Second
+
+	// SubscribeTimeout - How long single subscribe attempt waits for SUBACK.
+	// Overridable by `subscribeTimeout` config
+	SubscribeTimeout = 5 * time.Second
+
+	// GracefulShutdownTimeout - Bounds whole Stop. Overridable by `shutdownTimeout` config
+	GracefulShutdownTimeout = 1 * time.Second
+
+	// DisconnectQuiesce - How long broker client may take to finish in-flight
+	// work on disconnect. Capped by time left of Stop timeout. Overridable by
+	// `disconnectQuiesce` config
+	DisconnectQuiesce = 250 * time.Millisecond
 )
@@ -0,0 +1,663 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqttsn ...
+package mqttsn
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/powerunit-io/platform/config"
+	"github.com/powerunit-io/platform/connections"
+	"github.com/powerunit-io/platform/events"
+	"github.com/powerunit-io/platform/logging"
+	"github.com/powerunit-io/platform/managers"
+	"github.com/powerunit-io/platform/utils"
+
+	MQTT "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
+)
+
+// Connection - MQTT-SN gateway connection. Only subscribing side of the protocol
+// (qos 0 and 1) is implemented.
+type Connection struct {
+	*logging.Logger
+	*config.Config
+	managers.PhaseTracker
+
+	conn     net.Conn
+	lastSeen time.Time
+	msgID    uint16
+	connLock sync.Mutex
+
+	events   chan events.Event
+	done     chan bool
+	stop     chan bool
+	stopOnce sync.Once
+	routines sync.WaitGroup
+
+	connectedAt time.Time
+
+	topics     map[uint16]string
+	topicsLock sync.Mutex
+}
+
+// Start - Will connect to the gateway, subscribe to the topic and keep
+// reconnecting in background in case that gateway stops responding
+func (c *Connection) Start(done chan bool) error {
+	c.done = done
+	c.SetPhase(managers.PhaseConnecting)
+
+	connected := make(chan bool)
+
+	c.routines.Add(1)
+
+	go func() {
+		defer c.routines.Done()
+
+		first := true
+
+		for {
+			c.Info("Starting MQTT-SN (connection: %s) on (gateway: %s)...", c.Name(), c.GetGatewayAddr())
+
+			conn, err := c.connect()
+
+			if err != nil {
+				c.Disconnected()
+				c.Error(
+					"Failed to establish connection with mqtt-sn gateway for (worker: %s) due to (error: %s)",
+					c.Name(), err,
+				)
+
+				if c.stopping() {
+					return
+				}
+
+				time.Sleep(ReconnectInterval)
+				continue
+			}
+
+			if c.stopping() {
+				conn.Close()
+				return
+			}
+
+			c.connLock.Lock()
+			c.connectedAt = time.Now()
+			c.connLock.Unlock()
+
+			c.SetPhase(managers.PhaseConnected)
+
+			// Notify rest of the app that we're ready ...
+			if first {
+				first = false
+				close(connected)
+			}
+
+			c.routines.Add(1)
+			go c.keepAlive(conn)
+
+			err = c.receive(conn)
+
+			if c.stopping() {
+				c.Warning("Received stop signal for mqtt-sn (worker: %s). Will not attempt to restart worker ...", c.Name())
+				return
+			}
+
+			c.Disconnected()
+			c.Warning(
+				"Mqtt-sn (worker: %s) lost gateway connection due to (err: %s). Restarting loop in (interval: %s) ...",
+				c.Name(), err, ReconnectInterval,
+			)
+			time.Sleep(ReconnectInterval)
+		}
+	}()
+
+	select {
+	case <-connected:
+		c.Info(
+			"Successfully established mqtt-sn connection for (worker: %s) on (gateway: %s)",
+			c.Name(), c.GetGatewayAddr(),
+		)
+	case <-time.After(c.GetConnectTimeout()):
+		return fmt.Errorf(
+			"Could not establish mqtt-sn connection for (worker: %s) on (gateway: %s) due to initial connection (timeout: %s)",
+			c.Name(), c.GetGatewayAddr(), c.GetConnectTimeout(),
+		)
+	}
+
+	return nil
+}
+
+// connect - Will dial the gateway, perform CONNECT and SUBSCRIBE handshake
+func (c *Connection) connect() (net.Conn, error) {
+	conn, err := net.Dial("udp", c.GetGatewayAddr())
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.connLock.Lock()
+	c.conn = conn
+	c.lastSeen = time.Now()
+	c.connLock.Unlock()
+
+	c.topicsLock.Lock()
+	c.topics = make(map[uint16]string)
+	c.topicsLock.Unlock()
+
+	if err = c.handshake(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// handshake - Will connect and subscribe within AckTimeout each
+func (c *Connection) handshake(conn net.Conn) error {
+	keepAlive := uint16(c.GetKeepAlive() / time.Second)
+
+	if _, err := conn.Write(connectPacket(c.GetClientID(), keepAlive)); err != nil {
+		return err
+	}
+
+	connack, err := c.await(conn, msgConnack)
+
+	if err != nil {
+		return err
+	}
+
+	if len(connack.body) < 1 || connack.body[0] != accepted {
+		return fmt.Errorf("%s (packet: CONNACK) - (body: %v)", ErrRejected, connack.body)
+	}
+
+	topic := c.GetTopic()
+
+	if _, err = conn.Write(subscribePacket(c.nextMsgID(), topic, c.GetQoS())); err != nil {
+		return err
+	}
+
+	suback, err := c.await(conn, msgSuback)
+
+	if err != nil {
+		return err
+	}
+
+	topicID, ok := suback.uint16At(1)
+
+	if !ok || len(suback.body) < 6 || suback.body[5] != accepted {
+		return fmt.Errorf("%s (packet: SUBACK) - (body: %v)", ErrRejected, suback.body)
+	}
+
+	// Wildcard subscriptions are acknowledged with topic id 0, concrete topics
+	// are announced by the gateway through REGISTER
+	if topicID != 0 {
+		c.register(topicID, topic)
+	}
+
+	c.Info("Successfully subscribed (worker: %s) on (topic: %s)!", c.Name(), topic)
+
+	return nil
+}
+
+// await - Will read packets (handling unrelated ones) until one of kind arrives
+func (c *Connection) await(conn net.Conn, kind byte) (packet, error) {
+	conn.SetReadDeadline(time.Now().Add(AckTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	buf := make([]byte, maxPacketSize)
+
+	for {
+		n, err := conn.Read(buf)
+
+		if err != nil {
+			return packet{}, err
+		}
+
+		p, err := decode(append([]byte{}, buf[:n]...))
+
+		if err != nil {
+			c.Warning("Ignoring malformed mqtt-sn (worker: %s) packet due to (err: %s)", c.Name(), err)
+			continue
+		}
+
+		if p.kind == kind {
+			return p, nil
+		}
+
+		if err = c.handle(conn, p); err != nil {
+			return packet{}, err
+		}
+	}
+}
+
+// receive - Will handle received packets until connection fails
+func (c *Connection) receive(conn net.Conn) error {
+	buf := make([]byte, maxPacketSize)
+
+	for {
+		n, err := conn.Read(buf)
+
+		if err != nil {
+			return err
+		}
+
+		p, err := decode(append([]byte{}, buf[:n]...))
+
+		if err != nil {
+			c.Warning("Ignoring malformed mqtt-sn (worker: %s) packet due to (err: %s)", c.Name(), err)
+			continue
+		}
+
+		c.connLock.Lock()
+		c.lastSeen = time.Now()
+		c.connLock.Unlock()
+
+		if err = c.handle(conn, p); err != nil {
+			return err
+		}
+	}
+}
+
+// handle - Will react on single packet sent by the gateway
+func (c *Connection) handle(conn net.Conn, p packet) error {
+	switch p.kind {
+	case msgRegister:
+		topicID, _ := p.uint16At(0)
+		msgID, ok := p.uint16At(2)
+
+		if !ok {
+			return nil
+		}
+
+		c.register(topicID, string(p.body[4:]))
+		_, err := conn.Write(ackPacket(msgRegack, topicID, msgID, accepted))
+		return err
+
+	case msgPublish:
+		return c.publish(conn, p)
+
+	case msgPingreq:
+		_, err := conn.Write(encode(msgPingresp, nil))
+		return err
+
+	case msgDisconnect:
+		conn.Close()
+		return fmt.Errorf("mqtt-sn gateway sent DISCONNECT")
+	}
+
+	return nil
+}
+
+// publish - Will resolve topic of received PUBLISH, acknowledge it (qos 1) and
+// emit event
+func (c *Connection) publish(conn net.Conn, p packet) error {
+	topicID, _ := p.uint16At(1)
+	msgID, ok := p.uint16At(3)
+
+	if !ok {
+		return nil
+	}
+
+	flags := p.body[0]
+	qos := (flags & flagQoSMask) >> flagQoSShift
+
+	topic, known := c.resolve(flags&flagTopicIDType, topicID)
+
+	if qos == 1 {
+		code := byte(accepted)
+		if !known {
+			code = 0x02 // rejected: invalid topic id
+		}
+
+		if _, err := conn.Write(ackPacket(msgPuback, topicID, msgID, code)); err != nil {
+			return err
+		}
+	}
+
+	if !known {
+		c.Warning("Dropping mqtt-sn (worker: %s) message for unknown (topic_id: %d)", c.Name(), topicID)
+		return nil
+	}
+
+	c.Emit(&message{
+		qos: qos, retained: flags&flagRetain != 0, topic: topic, messageID: msgID,
+		payload: append([]byte{}, p.body[5:]...),
+	})
+
+	return nil
+}
+
+// keepAlive - Will ping the gateway and close connection once gateway did not
+// send anything for two keep alive intervals. Closing the connection makes the
+// receive loop reconnect.
+func (c *Connection) keepAlive(conn net.Conn) {
+	defer c.routines.Done()
+
+	interval := c.GetKeepAlive()
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-tick.C:
+			c.connLock.Lock()
+			current, lastSeen := c.conn, c.lastSeen
+			c.connLock.Unlock()
+
+			if current != conn {
+				return
+			}
+
+			if time.Since(lastSeen) > 2*interval {
+				c.Warning("Mqtt-sn (worker: %s) gateway stopped responding (last_seen: %s)", c.Name(), lastSeen)
+				conn.Close()
+				return
+			}
+
+			conn.Write(encode(msgPingreq, nil))
+		case <-c.stop:
+			return
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Emit - Will build event out of the message and push it to the events channel.
+// Event is released instead in case that connection stops before it's consumed.
+func (c *Connection) Emit(msg MQTT.Message) {
+	event, err := events.NewEvent(msg)
+
+	if err != nil {
+		c.Error("Could not handle received event due to (err: %s)", err)
+		event.Release()
+		return
+	}
+
+	c.Info("Event successfully created (data: %v)", event)
+
+	select {
+	case c.events <- event:
+	case <-c.stop:
+		event.Release()
+	case <-c.done:
+		event.Release()
+	}
+}
+
+// register - Will remember topic name announced for topic id
+func (c *Connection) register(topicID uint16, topic string) {
+	c.topicsLock.Lock()
+	defer c.topicsLock.Unlock()
+
+	c.topics[topicID] = topic
+}
+
+// resolve - Will return topic name for topic id of given type. Short topic
+// names are carried within topic id itself.
+func (c *Connection) resolve(kind byte, topicID uint16) (string, bool) {
+	if kind == topicShort {
+		return string([]byte{byte(topicID >> 8), byte(topicID)}), true
+	}
+
+	c.topicsLock.Lock()
+	defer c.topicsLock.Unlock()
+
+	topic, ok := c.topics[topicID]
+	return topic, ok
+}
+
+// nextMsgID - Will return next non zero message id
+func (c *Connection) nextMsgID() uint16 {
+	c.connLock.Lock()
+	defer c.connLock.Unlock()
+
+	c.msgID++
+	if c.msgID == 0 {
+		c.msgID++
+	}
+
+	return c.msgID
+}
+
+// Wait - Will block until reconnect loop and keep alive goroutines exited
+func (c *Connection) Wait() {
+	c.routines.Wait()
+}
+
+// stopping - Will return whenever Stop was called or done was signalled
+func (c *Connection) stopping() bool {
+	select {
+	case <-c.stop:
+		return true
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// DrainEvents - Will return event chan back for future processing by workers
+func (c *Connection) DrainEvents() chan events.Event {
+	return c.events
+}
+
+// WaitForMessage - Will return next event or error once timeout expires.
+// Intended for tests.
+func (c *Connection) WaitForMessage(timeout time.Duration) (events.Event, error) {
+	return events.WaitFor(c.DrainEvents(), timeout)
+}
+
+// WaitForMessageMatching - Will return next event satisfying the predicate or
+// error once timeout expires. Events not matching are consumed and released.
+// Intended for tests.
+func (c *Connection) WaitForMessageMatching(pred func(events.Event) bool, timeout time.Duration) (events.Event, error) {
+	return events.WaitForMatching(c.DrainEvents(), pred, timeout)
+}
+
+// Validate -
+func (c *Connection) Validate() error {
+	c.Info("Validating mqtt-sn configuration for (worker: %q)", c.Name())
+
+	data, ok := utils.AsStringMap(c.Config.Get("connection"))
+
+	if !ok {
+		return fmt.Errorf(
+			"Could not validate mqtt-sn worker as connection interface is missing or not a map (entry: %T)",
+			c.Config.Get("connection"),
+		)
+	}
+
+	addr, ok := data["gatewayAddr"].(string)
+
+	if !ok {
+		return fmt.Errorf(
+			"Could not validate mqtt-sn worker as connection gatewayAddr is not set. (connection_data: %q)",
+			c.Redact(data),
+		)
+	}
+
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return fmt.Errorf(
+			"Could not validate mqtt-sn worker as connection gatewayAddr is not valid host:port. (gateway_addr: %s)",
+			addr,
+		)
+	}
+
+	clientID, ok := data["clientId"].(string)
+
+	if !ok || len(clientID) < 1 || len(clientID) > MaxClientIDLength {
+		return fmt.Errorf(
+			"Could not validate mqtt-sn worker as connection clientId MUST be 1 to %d characters long. (client_id: %v)",
+			MaxClientIDLength, data["clientId"],
+		)
+	}
+
+	if topic, ok := data["topic"].(string); !ok || topic == "" {
+		return fmt.Errorf(
+			"Could not validate mqtt-sn worker as connection topic is not set. (connection_data: %q)",
+			c.Redact(data),
+		)
+	}
+
+	if qos, ok := data["qos"]; ok {
+		if value, ok := utils.AsInt(qos); !ok || value < 0 || value > 1 {
+			return fmt.Errorf(
+				"Could not validate mqtt-sn worker as connection qos is not valid. It MUST be 0 or 1. (qos: %v)",
+				qos,
+			)
+		}
+	}
+
+	for _, key := range []string{"keepAlive", "connectTimeout"} {
+		if value, ok := data[key]; ok {
+			if duration, ok := utils.AsDuration(value); !ok || duration < time.Second {
+				return fmt.Errorf(
+					"Could not validate mqtt-sn worker as connection %s is not valid duration of at least a second. (value: %v)",
+					key, value,
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+// connection - will return connection config block or empty one in case that
+// it's missing or malformed (Validate reports that)
+func (c *Connection) connection() map[string]interface{} {
+	if connection, ok := utils.AsStringMap(c.Config.Get("connection")); ok {
+		return connection
+	}
+
+	return map[string]interface{}{}
+}
+
+// GetGatewayAddr - will return gateway host:port
+func (c *Connection) GetGatewayAddr() string {
+	addr, _ := utils.AsString(c.connection()["gatewayAddr"])
+	return addr
+}
+
+// GetClientID -
+func (c *Connection) GetClientID() string {
+	clientID, _ := utils.AsString(c.connection()["clientId"])
+	return clientID
+}
+
+// ClientIdentity - will return client id together with the gateway it's used
+// against, see managers.ClientIdentified
+func (c *Connection) ClientIdentity() (string, []string) {
+	return c.GetClientID(), []string{c.GetGatewayAddr()}
+}
+
+// GetTopic - will return subscribed topic name
+func (c *Connection) GetTopic() string {
+	topic, _ := utils.AsString(c.connection()["topic"])
+	return topic
+}
+
+// GetQoS - will return subscription qos. Defaults to 0.
+func (c *Connection) GetQoS() byte {
+	qos, _ := utils.AsInt(c.connection()["qos"])
+	return byte(qos)
+}
+
+// GetKeepAlive - will return gateway ping interval
+func (c *Connection) GetKeepAlive() time.Duration {
+	if duration, ok := utils.AsDuration(c.connection()["keepAlive"]); ok && duration >= time.Second {
+		return duration
+	}
+
+	return KeepAlive
+}
+
+// GetConnectTimeout - will return how long Start waits for initial connection
+func (c *Connection) GetConnectTimeout() time.Duration {
+	if duration, ok := utils.AsDuration(c.connection()["connectTimeout"]); ok && duration >= time.Second {
+		return duration
+	}
+
+	return InitialConnectionTimeout
+}
+
+// Name -
+func (c *Connection) Name() string {
+	name, _ := utils.AsString(c.Config.Get("name"))
+	return name
+}
+
+// Kind -
+func (c *Connection) Kind() string {
+	return Kind
+}
+
+// Status - Will return current connection status
+func (c *Connection) Status() string {
+	switch c.Phase() {
+	case managers.PhaseStopped:
+		return managers.StatusStopped
+	case managers.PhaseConnected:
+		return managers.StatusConnected
+	}
+
+	return managers.StatusDisconnected
+}
+
+// Describe - Will return snapshot of connection configuration and state
+func (c *Connection) Describe() connections.ConnectionInfo {
+	info := connections.ConnectionInfo{
+		Kind:   c.Kind(),
+		Name:   c.Name(),
+		Broker: c.GetGatewayAddr(),
+		Topics: []string{c.GetTopic()},
+		QoS:    c.GetQoS(),
+		Status: c.Status(),
+		Phase:  c.Phase(),
+		Config: c.Redacted(),
+	}
+
+	if info.Status == managers.StatusConnected {
+		c.connLock.Lock()
+		connectedAt := c.connectedAt
+		c.connLock.Unlock()
+
+		info.Connected = true
+		info.Uptime = time.Since(connectedAt)
+	}
+
+	return info
+}
+
+// Adapter -
+func (c *Connection) Adapter() interface{} {
+	return &c
+}
+
+// Stop - Will send DISCONNECT to the gateway and close connection. Background
+// reconnect loop is stopped as well.
+func (c *Connection) Stop() error {
+	c.Warning("Stopping mqtt-sn (worker: %s) ...", c.Name())
+	defer c.SetPhase(managers.PhaseStopped)
+
+	c.stopOnce.Do(func() { close(c.stop) })
+
+	c.connLock.Lock()
+	conn := c.conn
+	c.conn = nil
+	c.connLock.Unlock()
+
+	if conn == nil {
+		c.Warning("Connection for mqtt-sn (worker: %s) is already closed.", c.Name())
+		return nil
+	}
+
+	conn.Write(encode(msgDisconnect, nil))
+
+	return conn.Close()
+}
@@ -0,0 +1,47 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqttsn ...
+package mqttsn
+
+// message - Received MQTT-SN publish satisfying MQTT.Message so events can be
+// built out of it the same way as out of mqtt messages
+type message struct {
+	duplicate bool
+	qos       byte
+	retained  bool
+	topic     string
+	messageID uint16
+	payload   []byte
+}
+
+// Duplicate -
+func (m *message) Duplicate() bool {
+	return m.duplicate
+}
+
+// Qos -
+func (m *message) Qos() byte {
+	return m.qos
+}
+
+// Retained -
+func (m *message) Retained() bool {
+	return m.retained
+}
+
+// Topic -
+func (m *message) Topic() string {
+	return m.topic
+}
+
+// MessageID -
+func (m *message) MessageID() uint16 {
+	return m.messageID
+}
+
+// Payload -
+func (m *message) Payload() []byte {
+	return m.payload
+}
@@ -0,0 +1,85 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqttsn ...
+package mqttsn
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// packet - Decoded MQTT-SN packet. Body excludes length and message type.
+type packet struct {
+	kind byte
+	body []byte
+}
+
+// encode - Will serialize packet prefixing it with length header. Three byte
+// length header is used for packets longer than 255 bytes.
+func encode(kind byte, body []byte) []byte {
+	length := len(body) + 2
+
+	if length <= 255 {
+		return append([]byte{byte(length), kind}, body...)
+	}
+
+	length += 2
+	header := []byte{0x01, byte(length >> 8), byte(length), kind}
+
+	return append(header, body...)
+}
+
+// decode - Will parse single datagram into packet
+func decode(data []byte) (packet, error) {
+	if len(data) < 2 {
+		return packet{}, fmt.Errorf("Could not decode mqtt-sn packet as it's too short (length: %d)", len(data))
+	}
+
+	length, offset := int(data[0]), 1
+
+	if data[0] == 0x01 {
+		if len(data) < 4 {
+			return packet{}, fmt.Errorf("Could not decode mqtt-sn packet as length header is truncated")
+		}
+
+		length, offset = int(binary.BigEndian.Uint16(data[1:3])), 3
+	}
+
+	if length != len(data) {
+		return packet{}, fmt.Errorf(
+			"Could not decode mqtt-sn packet as (length: %d) differs from datagram (size: %d)",
+			length, len(data),
+		)
+	}
+
+	return packet{kind: data[offset], body: data[offset+1:]}, nil
+}
+
+// uint16At - Will read big endian uint16 at offset or return false in case that
+// body is too short
+func (p packet) uint16At(offset int) (uint16, bool) {
+	if len(p.body) < offset+2 {
+		return 0, false
+	}
+
+	return binary.BigEndian.Uint16(p.body[offset : offset+2]), true
+}
+
+// connectPacket -
+func connectPacket(clientID string, keepAlive uint16) []byte {
+	body := []byte{flagCleanSession, protocolID, byte(keepAlive >> 8), byte(keepAlive)}
+	return encode(msgConnect, append(body, clientID...))
+}
+
+// subscribePacket - Subscribe by topic name (wildcards allowed)
+func subscribePacket(msgID uint16, topic string, qos byte) []byte {
+	body := []byte{(qos<<flagQoSShift)&flagQoSMask | topicNormal, byte(msgID >> 8), byte(msgID)}
+	return encode(msgSubscribe, append(body, topic...))
+}
+
+// ackPacket - REGACK / PUBACK body is topic id, message id and return code
+func ackPacket(kind byte, topicID, msgID uint16, code byte) []byte {
+	return encode(kind, []byte{byte(topicID >> 8), byte(topicID), byte(msgID >> 8), byte(msgID), code})
+}
@@ -0,0 +1,75 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mqttsn ...
+package mqttsn
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// Kind - Kind of the service reported to the managers
+	Kind = "mqttsn"
+
+	// MaxClientIDLength - MQTT-SN limits client id to 23 characters
+	MaxClientIDLength = 23
+
+	// Message types (MQTT-SN 1.2, section 5.2.2)
+	msgConnect    = 0x04
+	msgConnack    = 0x05
+	msgRegister   = 0x0A
+	msgRegack     = 0x0B
+	msgPublish    = 0x0C
+	msgPuback     = 0x0D
+	msgSubscribe  = 0x12
+	msgSuback     = 0x13
+	msgPingreq    = 0x16
+	msgPingresp   = 0x17
+	msgDisconnect = 0x18
+
+	// Flags (MQTT-SN 1.2, section 5.3.4)
+	flagRetain       = 0x10
+	flagCleanSession = 0x04
+	flagQoSShift     = 5
+	flagQoSMask      = 0x60
+	flagTopicIDType  = 0x03
+
+	// Topic id types
+	topicNormal     = 0x00
+	topicPredefined = 0x01
+	topicShort      = 0x02
+
+	// protocolID - Only protocol id defined by the specification
+	protocolID = 0x01
+
+	// accepted - Return code of accepted CONNACK, REGACK, PUBACK and SUBACK
+	accepted = 0x00
+
+	// maxPacketSize - Largest UDP datagram we are willing to receive
+	maxPacketSize = 65535
+)
+
+var (
+	// ErrNotConnected - Returned when operation requires established connection
+	ErrNotConnected = errors.New("mqtt-sn gateway connection is not established")
+
+	// ErrRejected - Returned when gateway refuses connection or subscription
+	ErrRejected = errors.New("mqtt-sn gateway rejected request")
+
+	// InitialConnectionTimeout - Overridable by `connectTimeout` config
+	InitialConnectionTimeout = 10 * time.Second
+
+	// ReconnectInterval - How long to wait before attempting to connect again
+	ReconnectInterval = 2 * time.Second
+
+	// AckTimeout - How long to wait for CONNACK / SUBACK from the gateway
+	AckTimeout = 5 * time.Second
+
+	// KeepAlive - Interval of PINGREQ sent to the gateway. Gateway is considered
+	// gone once no packet was received for two intervals. Overridable by
+	// `keepAlive` config
+	KeepAlive = 30 * time.Second
+)
@@ -15,7 +15,9 @@ import (
 
 	"github.com/jinzhu/gorm"
 	"github.com/powerunit-io/platform/config"
+	"github.com/powerunit-io/platform/connections"
 	"github.com/powerunit-io/platform/logging"
+	"github.com/powerunit-io/platform/managers"
 	"github.com/powerunit-io/platform/utils"
 )
 
@@ -23,13 +25,18 @@ import (
 type Connection struct {
 	*logging.Logger
 	*config.Config
+	managers.PhaseTracker
 	URI string
 	gorm.DB
+
+	connectedAt time.Time
 }
 
 // Start - Will connect to database and than try to reconnect in case that we get disconnected
 func (m *Connection) Start(done chan bool) error {
-	m.Info("Starting MSQL Connection: %s ...", m.URI)
+	m.Info("Starting MSQL Connection (name: %s) ...", m.Name())
+
+	m.SetPhase(managers.PhaseConnecting)
 
 	// Keep track of first connection, blocking, reconnect in background
 	started := make(chan bool)
@@ -43,11 +50,15 @@ func (m *Connection) Start(done chan bool) error {
 			err := m.Connect()
 
 			if err != nil {
+				m.Disconnected()
 				m.Logger.Error("Got Error while connecting against MYSQL: %s", err)
 				time.Sleep(5 * time.Second)
 				continue
 			}
 
+			m.connectedAt = time.Now()
+			m.SetPhase(managers.PhaseConnected)
+
 			// Notify of first connection
 			if !connected {
 				connected = true
@@ -57,6 +68,7 @@ func (m *Connection) Start(done chan bool) error {
 			go func() {
 				for {
 					if !m.IsConnected() {
+						m.Disconnected()
 						m.Logger.Error("Could not connect to MySQL server. Reconnecting in 5 seconds ...")
 						time.Sleep(5 * time.Second)
 						reconnect <- true
@@ -73,7 +85,7 @@ func (m *Connection) Start(done chan bool) error {
 
 	// Wait for background loop to connect for the first time
 	<-started
-	m.Info("MYSQL Connection successfully established: %v", m)
+	m.Info("MYSQL Connection successfully established (name: %s)", m.Name())
 
 	return nil
 }
@@ -85,8 +97,8 @@ func (m *Connection) Validate() error {
 	// @TODO - This needs proper regex validation ...
 	if len(m.URI) < 10 {
 		return fmt.Errorf(
-			"Failed to validate mysql connection (name: %s) uri. You've passed (uri: %s)",
-			m.Name(), m.URI,
+			"Failed to validate mysql connection (name: %s) uri. You've passed (uri_length: %d)",
+			m.Name(), len(m.URI),
 		)
 	}
 
@@ -95,7 +107,7 @@ func (m *Connection) Validate() error {
 
 // Connect - Will connect and ping connection in hope that all is ok
 func (m *Connection) Connect() error {
-	m.Logger.Debug("Connecting to MySQL server (uri: %s) ...", m.URI)
+	m.Logger.Debug("Connecting to MySQL server (name: %s) ...", m.Name())
 
 	var err error
 
@@ -147,6 +159,42 @@ func (m *Connection) IsConnected() bool {
 	return true
 }
 
+// Kind -
+func (m *Connection) Kind() string {
+	return Kind
+}
+
+// Status - Will return current connection status
+func (m *Connection) Status() string {
+	if m.DB.DB() == nil {
+		return managers.StatusStopped
+	}
+
+	if !m.IsConnected() {
+		return managers.StatusDisconnected
+	}
+
+	return managers.StatusConnected
+}
+
+// Describe - Will return snapshot of connection configuration and state
+func (m *Connection) Describe() connections.ConnectionInfo {
+	info := connections.ConnectionInfo{
+		Kind:   m.Kind(),
+		Name:   m.Name(),
+		Status: m.Status(),
+		Phase:  m.Phase(),
+		Config: m.Redacted(),
+	}
+
+	if info.Status == managers.StatusConnected {
+		info.Connected = true
+		info.Uptime = time.Since(m.connectedAt)
+	}
+
+	return info
+}
+
 // Adapter -
 func (m *Connection) Adapter() interface{} {
 	return m
@@ -154,15 +202,19 @@ func (m *Connection) Adapter() interface{} {
 
 // Name -
 func (m *Connection) Name() string {
-	return m.Config.Get("name").(string)
+	name, _ := utils.AsString(m.Config.Get("name"))
+	return name
 }
 
 // Stop - Will close MySQL connection if we ever need it
 func (m *Connection) Stop() error {
-	m.Warning(
-		"Closing MySQL connection for (name: %s) - (uri: %s) ...",
-		m.Name(), m.URI,
-	)
+	m.Warning("Closing MySQL connection for (name: %s) ...", m.Name())
+	defer m.SetPhase(managers.PhaseStopped)
+
+	if m.DB.DB() == nil {
+		m.Warning("MySQL connection for (name: %s) is already closed.", m.Name())
+		return nil
+	}
 
 	return m.Close()
 }
@@ -7,28 +7,30 @@ package mysql
 
 import (
 	"github.com/powerunit-io/platform/config"
+	"github.com/powerunit-io/platform/connections"
 	"github.com/powerunit-io/platform/logging"
-	"github.com/powerunit-io/platform/managers"
+	"github.com/powerunit-io/platform/utils"
 )
 
 // Adapter -
 type Adapter interface {
-	managers.Service
+	connections.Connection
 }
 
 // NewAdapter -
 func NewAdapter(n string, conf map[string]interface{}, logger *logging.Logger) (Adapter, error) {
 
-	cnf, err := config.NewConfigManager(n, conf)
+	cnf, name, err := config.NewNamedConfigManager(n, conf)
 
 	if err != nil {
 		logger.Error("Failed to configure mysql configuration manager for (manager: %s) (error: %s)", n, err)
 		return nil, err
 	}
 
-	cnf.Set("name", n)
+	cnf.Set("name", name)
+	cnf.MarkSensitive("uri")
 
-	return Adapter(&Connection{
-		Logger: logger, Config: cnf, URI: cnf.Get("uri").(string),
-	}), nil
+	uri, _ := utils.AsString(cnf.Get("uri"))
+
+	return Adapter(&Connection{Logger: logger, Config: cnf, URI: uri}), nil
 }
@@ -0,0 +1,148 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package adaptertest ...
+package adaptertest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/powerunit-io/platform/events"
+	"github.com/powerunit-io/platform/managers"
+)
+
+// Factory - Will build fresh, not started, adapter out of the configuration
+type Factory func(name string, conf map[string]interface{}) (managers.Service, error)
+
+// Emitter - Will push payload through adapter fake transport as if it was
+// received on the topic
+type Emitter func(service managers.Service, topic string, payload []byte)
+
+// Suite - Describes adapter under the conformance test
+type Suite struct {
+	// New - Adapter factory, usually thin wrapper around adapter NewAdapter
+	New Factory
+
+	// ValidConfig - Configuration which MUST pass validation
+	ValidConfig map[string]interface{}
+
+	// InvalidConfigs - Configurations (by description) which MUST fail validation
+	InvalidConfigs map[string]map[string]interface{}
+
+	// Emit - Optional fake transport. When set, adapter MUST expose received
+	// events through DrainEvents
+	Emit Emitter
+
+	// Payload - Payload emitted through fake transport. MUST produce valid event.
+	Payload []byte
+
+	// Timeout - How long to wait for emitted event. Defaults to 1 second.
+	Timeout time.Duration
+}
+
+// drainer - Adapters exposing their events channel
+type drainer interface {
+	DrainEvents() chan events.Event
+}
+
+// RunConformance - Will run baseline correctness tests against the adapter.
+// Configuration maps are copied for each created adapter, and adapters are
+// created with unique names as configuration managers are registered by name.
+func RunConformance(t *testing.T, suite Suite) {
+	counter := 0
+
+	build := func(conf map[string]interface{}) managers.Service {
+		counter++
+		name := fmt.Sprintf("conformance-%s-%d", t.Name(), counter)
+
+		service, err := suite.New(name, copyConfig(conf))
+
+		if err != nil {
+			t.Fatalf("Could not create adapter (name: %s) due to (err: %s)", name, err)
+		}
+
+		if service.Name() != name {
+			t.Errorf("Expected adapter (name: %s) but got (name: %s)", name, service.Name())
+		}
+
+		return service
+	}
+
+	t.Run("ValidConfig", func(t *testing.T) {
+		if err := build(suite.ValidConfig).Validate(); err != nil {
+			t.Errorf("Expected valid config to pass validation but got (err: %s)", err)
+		}
+	})
+
+	for description, conf := range suite.InvalidConfigs {
+		conf := conf
+
+		t.Run("InvalidConfig/"+description, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("Validate panicked instead of returning error (panic: %v)", r)
+				}
+			}()
+
+			if err := build(conf).Validate(); err == nil {
+				t.Errorf("Expected invalid config (%s) to fail validation", description)
+			}
+		})
+	}
+
+	t.Run("StopIdempotency", func(t *testing.T) {
+		service := build(suite.ValidConfig)
+
+		for i := 0; i < 2; i++ {
+			if err := service.Stop(); err != nil {
+				t.Errorf("Expected stop (attempt: %d) of not started adapter to succeed but got (err: %s)", i+1, err)
+			}
+		}
+	})
+
+	if suite.Emit == nil {
+		return
+	}
+
+	t.Run("EventEmission", func(t *testing.T) {
+		service := build(suite.ValidConfig)
+
+		d, ok := service.(drainer)
+
+		if !ok {
+			t.Fatalf("Adapter with fake transport MUST expose DrainEvents")
+		}
+
+		timeout := suite.Timeout
+		if timeout == 0 {
+			timeout = time.Second
+		}
+
+		go suite.Emit(service, "adaptertest/conformance", suite.Payload)
+
+		select {
+		case <-d.DrainEvents():
+		case <-time.After(timeout):
+			t.Errorf("Expected event to be emitted within (timeout: %s)", timeout)
+		}
+	})
+}
+
+// copyConfig - Will deep copy configuration so adapters never share maps
+func copyConfig(conf map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(conf))
+
+	for key, value := range conf {
+		if nested, ok := value.(map[string]interface{}); ok {
+			copied[key] = copyConfig(nested)
+			continue
+		}
+
+		copied[key] = value
+	}
+
+	return copied
+}
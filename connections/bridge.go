@@ -0,0 +1,103 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package connections ...
+package connections
+
+import (
+	"fmt"
+
+	"github.com/powerunit-io/platform/events"
+	"github.com/powerunit-io/platform/logging"
+	"github.com/powerunit-io/platform/metrics"
+)
+
+// Source - Connection events can be drained from
+type Source interface {
+	DrainEvents() chan events.Event
+}
+
+// Sink - Connection events can be published to
+type Sink interface {
+	Publish(topic string, qos byte, retained bool, payload interface{}) error
+}
+
+// BridgeOptions - Bridge configuration
+type BridgeOptions struct {
+	// Topics - Maps source topics to destination ones. Topics which are not
+	// mapped are preserved.
+	Topics map[string]string
+
+	// QoS - Qos events are published to the destination with
+	QoS byte
+
+	// Retained - Whenever events are published to the destination as retained
+	Retained bool
+
+	// Stop - Bridge returns once it's closed. Bridge runs until source events
+	// channel is closed otherwise.
+	Stop chan bool
+
+	// Logger - Optional logger publish failures are reported to
+	Logger *logging.Logger
+}
+
+// Bridge - Will drain events from src and republish their payload to dst (e.g.
+// while migrating between brokers). Events are published one by one, so slow
+// destination applies backpressure on the source. Events which failed to publish
+// are counted and skipped. Blocks until opts.Stop is closed or src events channel
+// is closed.
+func Bridge(src, dst Connection, opts BridgeOptions) error {
+	source, ok := src.(Source)
+
+	if !ok {
+		return fmt.Errorf("Could not bridge (source: %s) as it does not provide events to drain", src.Name())
+	}
+
+	sink, ok := dst.(Sink)
+
+	if !ok {
+		return fmt.Errorf("Could not bridge to (destination: %s) as it does not support publishing", dst.Name())
+	}
+
+	received := source.DrainEvents()
+
+	if received == nil {
+		return fmt.Errorf("Could not bridge (source: %s) as its events are already consumed", src.Name())
+	}
+
+	labels := map[string]string{"source": src.Name(), "destination": dst.Name()}
+
+	for {
+		select {
+		case event, ok := <-received:
+			if !ok {
+				return nil
+			}
+
+			topic := event.Topic()
+
+			if mapped, ok := opts.Topics[topic]; ok {
+				topic = mapped
+			}
+
+			if err := sink.Publish(topic, opts.QoS, opts.Retained, event.Payload()); err != nil {
+				metrics.Inc(BridgeFailuresMetric, labels)
+
+				if opts.Logger != nil {
+					opts.Logger.Error(
+						"Could not bridge event from (source: %s) to (destination: %s) - (topic: %s) due to (err: %s)",
+						src.Name(), dst.Name(), topic, err,
+					)
+				}
+			} else {
+				metrics.Inc(BridgedMetric, labels)
+			}
+
+			event.Release()
+		case <-opts.Stop:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package connections ...
+package connections
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/powerunit-io/platform/managers"
+)
+
+// HealthChecker - Optional interface of connections reporting their health on
+// their own instead of through readiness
+type HealthChecker interface {
+	Healthy() bool
+}
+
+// Health - Health report of all connections served by HealthHandler
+type Health struct {
+	Healthy     bool                        `json:"healthy"`
+	Connections map[string]ConnectionHealth `json:"connections"`
+}
+
+// ConnectionHealth - Health of single connection
+type ConnectionHealth struct {
+	Healthy bool   `json:"healthy"`
+	Phase   string `json:"phase,omitempty"`
+	Status  string `json:"status,omitempty"`
+}
+
+// HealthHandler - Will return http handler (e.g. for `/healthz` probe) reporting
+// health of each connection attached to the manager as json. Connection is
+// healthy when ready, see managers.BaseManager.ReadyDetail, unless it's
+// HealthChecker. Responds with 200 when all connections are healthy and 503
+// otherwise.
+func HealthHandler(m Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		health := Health{Healthy: true, Connections: map[string]ConnectionHealth{}}
+		services := m.All()
+
+		for name, ready := range m.ReadyDetail() {
+			if checker, ok := services[name].(HealthChecker); ok {
+				ready = checker.Healthy()
+			}
+
+			connection := ConnectionHealth{Healthy: ready}
+
+			if phased, ok := services[name].(managers.Phased); ok {
+				connection.Phase = phased.Phase().String()
+			}
+
+			if inspectable, ok := services[name].(managers.Inspectable); ok {
+				connection.Status = inspectable.Status()
+			}
+
+			health.Connections[name] = connection
+			health.Healthy = health.Healthy && ready
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if !health.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		json.NewEncoder(w).Encode(health)
+	}
+}
@@ -6,10 +6,36 @@
 package connections
 
 import (
+	"time"
+
 	"github.com/powerunit-io/platform/logging"
 	"github.com/powerunit-io/platform/managers"
 )
 
+// Connection - Interface shared by all connection adapters
+type Connection interface {
+	managers.Service
+
+	// Describe - Will return configuration and state snapshot of the connection
+	Describe() ConnectionInfo
+}
+
+// ConnectionInfo - Machine readable (json) connection snapshot. Credentials are
+// always redacted.
+type ConnectionInfo struct {
+	Kind      string                 `json:"kind"`
+	Name      string                 `json:"name"`
+	Broker    string                 `json:"broker,omitempty"`
+	Topics    []string               `json:"topics,omitempty"`
+	QoS       byte                   `json:"qos"`
+	Status    string                 `json:"status"`
+	Phase     managers.Phase         `json:"phase"`
+	Connected bool                   `json:"connected"`
+	Uptime    time.Duration          `json:"uptime"`
+	Tags      map[string]string      `json:"tags,omitempty"`
+	Config    map[string]interface{} `json:"config"`
+}
+
 // Manager -
 type Manager interface {
 	managers.Manager
@@ -0,0 +1,14 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package connections ...
+package connections
+
+const (
+	// BridgedMetric - Name of the counter of events republished by Bridge
+	BridgedMetric = "bridged_events"
+
+	// BridgeFailuresMetric - Name of the counter of events Bridge failed to publish
+	BridgeFailuresMetric = "bridge_failures"
+)
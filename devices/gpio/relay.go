@@ -5,7 +5,10 @@
 // Package gpio ...
 package gpio
 
-import "github.com/powerunit-io/platform/config"
+import (
+	"github.com/powerunit-io/platform/config"
+	"github.com/powerunit-io/platform/utils"
+)
 
 // Relay -
 type Relay struct {
@@ -19,5 +22,6 @@ func (s *Relay) Pin() uint64 {
 
 // Name - Will return name of this relay
 func (s *Relay) Name() string {
-	return s.Config.Get("name").(string)
+	name, _ := utils.AsString(s.Config.Get("name"))
+	return name
 }
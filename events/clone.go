@@ -0,0 +1,107 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package events ...
+package events
+
+import MQTT "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
+
+// Clone - Will return independent copy of the event, safe to be handed over to
+// another handler (fan-out). Deep copied are Data (including nested maps and
+// lists) and message payload. Scalar fields are copied by value. Decoded value
+// (see Decoded) is shared and MUST be treated as read only. Clone has its own
+// pooled data and may be released independently of the original.
+func (e *Event) Clone() Event {
+	clone := *e
+	clone.Data = acquireData()
+
+	for key, value := range e.Data {
+		clone.Data[key] = cloneValue(value)
+	}
+
+	if e.Message != nil {
+		clone.Message = cloneMessage(e.Message)
+	}
+
+	return clone
+}
+
+// cloneValue - Will deep copy json decoded value
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+
+		for key, item := range v {
+			copied[key] = cloneValue(item)
+		}
+
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+
+		for i, item := range v {
+			copied[i] = cloneValue(item)
+		}
+
+		return copied
+	}
+
+	return value
+}
+
+// message - Detached copy of the received mqtt message
+type message struct {
+	duplicate bool
+	qos       byte
+	retained  bool
+	topic     string
+	messageID uint16
+	payload   []byte
+}
+
+// cloneMessage - Will copy message including its payload
+func cloneMessage(msg MQTT.Message) MQTT.Message {
+	payload := make([]byte, len(msg.Payload()))
+	copy(payload, msg.Payload())
+
+	return &message{
+		duplicate: msg.Duplicate(),
+		qos:       msg.Qos(),
+		retained:  msg.Retained(),
+		topic:     msg.Topic(),
+		messageID: msg.MessageID(),
+		payload:   payload,
+	}
+}
+
+// Duplicate -
+func (m *message) Duplicate() bool {
+	return m.duplicate
+}
+
+// Qos -
+func (m *message) Qos() byte {
+	return m.qos
+}
+
+// Retained -
+func (m *message) Retained() bool {
+	return m.retained
+}
+
+// Topic -
+func (m *message) Topic() string {
+	return m.topic
+}
+
+// MessageID -
+func (m *message) MessageID() uint16 {
+	return m.messageID
+}
+
+// Payload -
+func (m *message) Payload() []byte {
+	return m.payload
+}
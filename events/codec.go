@@ -0,0 +1,261 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package events ...
+package events
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+)
+
+// Codec - Serialization format of events persisted or republished outside of
+// the broker (capture, replay, dead letters). Encoded is the transport message
+// (topic, payload, ...) together with time event was received; event fields
+// are carried within the payload.
+type Codec interface {
+	Encode(e Event) ([]byte, error)
+	Decode(data []byte) (Event, error)
+}
+
+// JSONCodec - Will encode event as single line json record {"topic", "payload",
+// "encoding", "timestamp", "qos", "retained", "seq", "source"}. Payload is stored
+// as json value when it's valid json. Otherwise it's stored as json string with
+// encoding telling whenever it holds the text itself (TextPayloadEncoding) or,
+// for payloads which are not valid utf-8, its base64 (Base64PayloadEncoding).
+// Timestamp is either RFC3339 string or unix seconds.
+type JSONCodec struct{}
+
+// jsonRecord -
+type jsonRecord struct {
+	Topic     string          `json:"topic"`
+	Payload   json.RawMessage `json:"payload"`
+	Encoding  string          `json:"encoding,omitempty"`
+	Timestamp interface{}     `json:"timestamp,omitempty"`
+	Qos       byte            `json:"qos,omitempty"`
+	Retained  bool            `json:"retained,omitempty"`
+	Sequence  uint64          `json:"seq,omitempty"`
+	Source    string          `json:"source,omitempty"`
+}
+
+// Encode -
+func (JSONCodec) Encode(e Event) ([]byte, error) {
+	if e.Message == nil {
+		return nil, fmt.Errorf("Could not encode (event: %v) as it carries no message", e)
+	}
+
+	rec := jsonRecord{
+		Topic:    e.Topic(),
+		Payload:  json.RawMessage(e.Payload()),
+		Qos:      e.Qos(),
+		Retained: e.Retained,
+		Sequence: e.Sequence,
+		Source:   e.Source,
+	}
+
+	if !json.Valid(e.Payload()) {
+		rec.Encoding = TextPayloadEncoding
+		text := string(e.Payload())
+
+		if !utf8.Valid(e.Payload()) {
+			rec.Encoding = Base64PayloadEncoding
+			text = base64.StdEncoding.EncodeToString(e.Payload())
+		}
+
+		encoded, _ := json.Marshal(text)
+		rec.Payload = json.RawMessage(encoded)
+	}
+
+	if !e.ReceivedAt.IsZero() {
+		rec.Timestamp = e.ReceivedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	data, err := json.Marshal(rec)
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not encode event for (topic: %s) due to (err: %s)", e.Topic(), err)
+	}
+
+	return data, nil
+}
+
+// Decode -
+func (JSONCodec) Decode(data []byte) (Event, error) {
+	var rec jsonRecord
+
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return Event{}, fmt.Errorf("Could not decode json event record due to (err: %s)", err)
+	}
+
+	msg := &message{topic: rec.Topic, payload: []byte(rec.Payload), qos: rec.Qos, retained: rec.Retained}
+
+	if rec.Encoding != "" {
+		payload, err := decodePayload(rec)
+
+		if err != nil {
+			return Event{}, err
+		}
+
+		msg.payload = payload
+	}
+
+	var receivedAt time.Time
+
+	switch ts := rec.Timestamp.(type) {
+	case float64:
+		receivedAt = time.Unix(0, int64(ts*float64(time.Second)))
+	case string:
+		var err error
+
+		if receivedAt, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+			return Event{}, fmt.Errorf("Could not parse json event record (timestamp: %s)", ts)
+		}
+	}
+
+	e := restore(msg, receivedAt)
+	e.Sequence, e.Source = rec.Sequence, rec.Source
+
+	return e, nil
+}
+
+// decodePayload - Will return payload of json record stored as json string
+func decodePayload(rec jsonRecord) ([]byte, error) {
+	var text string
+
+	if err := json.Unmarshal(rec.Payload, &text); err != nil {
+		return nil, fmt.Errorf("Could not decode json event record (encoding: %s) payload due to (err: %s)", rec.Encoding, err)
+	}
+
+	switch rec.Encoding {
+	case TextPayloadEncoding:
+		return []byte(text), nil
+	case Base64PayloadEncoding:
+		payload, err := base64.StdEncoding.DecodeString(text)
+
+		if err != nil {
+			return nil, fmt.Errorf("Could not decode json event record base64 payload due to (err: %s)", err)
+		}
+
+		return payload, nil
+	}
+
+	return nil, fmt.Errorf("Could not decode json event record payload of unknown (encoding: %s)", rec.Encoding)
+}
+
+// BinaryCodec - Will encode event as length prefixed record: big endian uint64
+// timestamp (unix nanoseconds, 0 if unknown), uint16 topic length, topic,
+// uint32 payload length and payload. QoS, retained flag, sequence and source
+// are not kept.
+type BinaryCodec struct{}
+
+// binaryHeader -
+type binaryHeader struct {
+	Timestamp   uint64
+	TopicLength uint16
+}
+
+// Encode -
+func (BinaryCodec) Encode(e Event) ([]byte, error) {
+	if e.Message == nil {
+		return nil, fmt.Errorf("Could not encode (event: %v) as it carries no message", e)
+	}
+
+	if len(e.Topic()) > 0xffff {
+		return nil, fmt.Errorf("Could not encode event as (topic_length: %d) exceeds binary record limit", len(e.Topic()))
+	}
+
+	header := binaryHeader{TopicLength: uint16(len(e.Topic()))}
+
+	if !e.ReceivedAt.IsZero() {
+		header.Timestamp = uint64(e.ReceivedAt.UnixNano())
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, BinaryHeaderSize+len(e.Topic())+len(e.Payload())))
+
+	binary.Write(buf, binary.BigEndian, header)
+	buf.WriteString(e.Topic())
+	binary.Write(buf, binary.BigEndian, uint32(len(e.Payload())))
+	buf.Write(e.Payload())
+
+	return buf.Bytes(), nil
+}
+
+// Decode -
+func (c BinaryCodec) Decode(data []byte) (Event, error) {
+	r := bytes.NewReader(data)
+	e, err := c.Read(r)
+
+	if err == nil && r.Len() > 0 {
+		e.Release()
+		return Event{}, fmt.Errorf("Could not decode binary event record as it has (trailing: %d) bytes", r.Len())
+	}
+
+	return e, err
+}
+
+// Read - Will read single record out of the stream of records. io.EOF is
+// returned once stream ends on the record boundary.
+func (BinaryCodec) Read(r io.Reader) (Event, error) {
+	var header binaryHeader
+
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return Event{}, err
+	}
+
+	topic := make([]byte, header.TopicLength)
+	if _, err := io.ReadFull(r, topic); err != nil {
+		return Event{}, unexpected(err)
+	}
+
+	var length uint32
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return Event{}, unexpected(err)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return Event{}, unexpected(err)
+	}
+
+	var receivedAt time.Time
+
+	if header.Timestamp > 0 {
+		receivedAt = time.Unix(0, int64(header.Timestamp))
+	}
+
+	return restore(&message{topic: string(topic), payload: payload}, receivedAt), nil
+}
+
+// GetCodec - Will return codec registered under the name (see Codecs)
+func GetCodec(name string) (Codec, bool) {
+	codec, ok := Codecs[name]
+	return codec, ok
+}
+
+// restore - Will build event out of decoded message. Event fields are parsed
+// out of the payload when it holds an event; other payloads (raw captures) are
+// kept as message only, see Validate.
+func restore(msg *message, receivedAt time.Time) Event {
+	e := Event{Message: msg, Data: acquireData(), Retained: msg.retained, ReceivedAt: receivedAt}
+
+	// Payload not being an event is not a decoding error
+	json.Unmarshal(msg.payload, &e)
+
+	return e
+}
+
+// unexpected - EOF in the middle of the record means truncated stream
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
@@ -0,0 +1,14 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package events ...
+package events
+
+// Decryptor - Will return plaintext of payload received on the topic. Returned
+// error means that message should be dropped.
+type Decryptor func(topic string, ciphertext []byte) ([]byte, error)
+
+// Encryptor - Will return ciphertext of payload about to be published on the
+// topic
+type Encryptor func(topic string, plaintext []byte) ([]byte, error)
@@ -6,9 +6,9 @@
 package events
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	MQTT "git.eclipse.org/gitroot/paho/org.eclipse.paho.mqtt.golang.git"
 	"github.com/powerunit-io/platform/utils"
@@ -17,11 +17,41 @@ import (
 // Event -
 type Event struct {
 	MQTT.Message
-	EventType string                 `json:"type"`
-	DeviceID  string                 `json:"device_id"`
-	Data      map[string]interface{} `json:"data"`
+	EventType     string                 `json:"type"`
+	DeviceID      string                 `json:"device_id"`
+	CorrelationID string                 `json:"correlation_id,omitempty"`
+	Data          map[string]interface{} `json:"data"`
+
+	// Retained - Whenever event was delivered as broker retained (last known)
+	// message rather than live update
+	Retained bool `json:"-"`
+
+	// ReceivedAt - Time when the message was received from the transport
+	ReceivedAt time.Time `json:"-"`
+
+	// Source - Name of the connection event came from, set once events of
+	// several connections are merged (see managers.BaseManager.AllEvents)
+	Source string `json:"-"`
+
+	// Sequence - Monotonic ordering key of recorded events, zero for live ones
+	// (see file.Recorder)
+	Sequence uint64 `json:"-"`
+
+	// ConnValues - Context of the connection event was received over, see
+	// ConnValue
+	ConnValues ConnValues `json:"-"`
+
+	decoded interface{}
 }
 
+// Handler - Event processing callback. Returned error means that event could
+// not be processed.
+type Handler func(e Event) error
+
+// Transform - Event transformation (decrypt, remap fields, ...). Returned error
+// means that event should be dropped.
+type Transform func(e Event) (Event, error)
+
 // Validate -
 func (e *Event) Validate() error {
 
@@ -35,16 +65,32 @@ func (e *Event) Validate() error {
 	return nil
 }
 
-// NewEvent -
+// Age - Will return how long ago the event was received. Zero is returned for
+// events which were not built through NewEvent.
+func (e *Event) Age() time.Duration {
+	if e.ReceivedAt.IsZero() {
+		return 0
+	}
+
+	return time.Since(e.ReceivedAt)
+}
+
+// NewEvent - Will build event out of received mqtt message. Event data is taken
+// from the pool, see Event.Release. Event returned with nil error always carries
+// the message and data; ErrNilMessage is returned for nil message.
 func NewEvent(msg MQTT.Message) (Event, error) {
-	e := Event{Message: msg}
+	if msg == nil {
+		return Event{}, ErrNilMessage
+	}
 
-	decoder := json.NewDecoder(bytes.NewReader(msg.Payload()))
+	e := Event{Message: msg, Data: acquireData(), ReceivedAt: time.Now()}
 
-	if err := decoder.Decode(&e); err != nil {
+	if err := json.Unmarshal(msg.Payload(), &e); err != nil {
 		return e, err
 	}
 
+	e.Retained = msg.Retained()
+
 	if err := e.Validate(); err != nil {
 		return e, err
 	}
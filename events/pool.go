@@ -0,0 +1,40 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package events ...
+package events
+
+import "sync"
+
+// dataPool - Keeps event data maps around between messages so that high
+// throughput connections do not allocate a fresh map per received message
+var dataPool = sync.Pool{
+	New: func() interface{} {
+		return make(map[string]interface{})
+	},
+}
+
+// acquireData - Will return empty data map from the pool
+func acquireData() map[string]interface{} {
+	return dataPool.Get().(map[string]interface{})
+}
+
+// Release - Will return event data back to the pool. Calling it is optional, but
+// workers processing large volumes of events should call it as soon as they are
+// done with the event. Event (including its data) MUST NOT be used after release.
+func (e *Event) Release() {
+	if e.Data == nil {
+		return
+	}
+
+	for key := range e.Data {
+		delete(e.Data, key)
+	}
+
+	dataPool.Put(e.Data)
+
+	e.Message = nil
+	e.Data = nil
+	e.ConnValues = nil
+}
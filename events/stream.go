@@ -0,0 +1,217 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package events ...
+package events
+
+import (
+	"context"
+	"time"
+)
+
+// stage - Single step of the stream pipeline, reading from in until it's closed
+// or context is done
+type stage func(ctx context.Context, in <-chan Event) <-chan Event
+
+// Stream - Composable pipeline over the channel of events (e.g. connection
+// DrainEvents). Operations only describe the pipeline; nothing is consumed until
+// terminal ForEach is invoked. Stages are unbuffered (unless Buffer is used) so
+// slow handler holds back the source the same way ranging over channel does.
+// Events dropped by the stream (Filter, cancellation) are released.
+type Stream struct {
+	source <-chan Event
+	stages []stage
+}
+
+// NewStream - Will build stream reading from the channel
+func NewStream(source <-chan Event) *Stream {
+	return &Stream{source: source}
+}
+
+// Filter - Will pass only events satisfying the predicate
+func (s *Stream) Filter(pred func(Event) bool) *Stream {
+	return s.then(func(ctx context.Context, e Event, out chan<- Event) bool {
+		if !pred(e) {
+			e.Release()
+			return true
+		}
+
+		return send(ctx, e, out)
+	})
+}
+
+// Map - Will replace each event by the one returned by fn
+func (s *Stream) Map(fn func(Event) Event) *Stream {
+	return s.then(func(ctx context.Context, e Event, out chan<- Event) bool {
+		return send(ctx, fn(e), out)
+	})
+}
+
+// Throttle - Will pass at most one event per interval. Events are delayed, not
+// dropped, so throttled stream backpressures the source.
+func (s *Stream) Throttle(interval time.Duration) *Stream {
+	var last time.Time
+
+	return s.then(func(ctx context.Context, e Event, out chan<- Event) bool {
+		if wait := interval - time.Since(last); !last.IsZero() && wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				e.Release()
+				return false
+			}
+		}
+
+		last = time.Now()
+		return send(ctx, e, out)
+	})
+}
+
+// Buffer - Will decouple following stages from preceding ones by buffer of
+// the size, so short handler stalls do not hold back the source
+func (s *Stream) Buffer(size int) *Stream {
+	return s.with(func(ctx context.Context, in <-chan Event) <-chan Event {
+		out := make(chan Event, size)
+		go pump(ctx, in, out, send)
+		return out
+	})
+}
+
+// Batch - Will group events into batches of up to size events. Batch is
+// emitted once it's full or wait elapsed since its first event.
+func (s *Stream) Batch(size int, wait time.Duration) *BatchStream {
+	return &BatchStream{stream: s, size: size, wait: wait}
+}
+
+// ForEach - Will run the pipeline invoking fn for each event which made it
+// through. Blocks until source is closed (nil is returned) or context is done
+// (context error is returned).
+func (s *Stream) ForEach(ctx context.Context, fn func(Event)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	in := s.run(ctx)
+
+	for {
+		select {
+		case e, ok := <-in:
+			if !ok {
+				return ctx.Err()
+			}
+
+			fn(e)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// run - Will start stages and return output of the last one
+func (s *Stream) run(ctx context.Context) <-chan Event {
+	in := s.source
+
+	for _, stage := range s.stages {
+		in = stage(ctx, in)
+	}
+
+	return in
+}
+
+// with - Will return copy of the stream extended by the stage
+func (s *Stream) with(next stage) *Stream {
+	stages := append(append([]stage{}, s.stages...), next)
+	return &Stream{source: s.source, stages: stages}
+}
+
+// then - Will extend the stream by unbuffered stage handling events one by one.
+// Handler returns false in case that pipeline should stop.
+func (s *Stream) then(handle func(ctx context.Context, e Event, out chan<- Event) bool) *Stream {
+	return s.with(func(ctx context.Context, in <-chan Event) <-chan Event {
+		out := make(chan Event)
+		go pump(ctx, in, out, handle)
+		return out
+	})
+}
+
+// BatchStream - Stream of event batches, see Stream.Batch
+type BatchStream struct {
+	stream *Stream
+	size   int
+	wait   time.Duration
+}
+
+// ForEach - Will run the pipeline invoking fn for each batch, see
+// Stream.ForEach. Partial batch is flushed once source is closed.
+func (b *BatchStream) ForEach(ctx context.Context, fn func([]Event)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	in := b.stream.run(ctx)
+	batch := make([]Event, 0, b.size)
+	var timeout <-chan time.Time
+
+	flush := func() {
+		if len(batch) > 0 {
+			fn(batch)
+			batch = make([]Event, 0, b.size)
+		}
+
+		timeout = nil
+	}
+
+	for {
+		select {
+		case e, ok := <-in:
+			if !ok {
+				flush()
+				return ctx.Err()
+			}
+
+			if len(batch) == 0 {
+				timeout = time.After(b.wait)
+			}
+
+			if batch = append(batch, e); len(batch) >= b.size {
+				flush()
+			}
+		case <-timeout:
+			flush()
+		case <-ctx.Done():
+			for _, e := range batch {
+				e.Release()
+			}
+
+			return ctx.Err()
+		}
+	}
+}
+
+// pump - Will feed events from in through handle until in is closed, handle
+// gives up or context is done. Out is closed on return.
+func pump(ctx context.Context, in <-chan Event, out chan<- Event, handle func(ctx context.Context, e Event, out chan<- Event) bool) {
+	defer close(out)
+
+	for {
+		select {
+		case e, ok := <-in:
+			if !ok || !handle(ctx, e, out) {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// send - Will push event to out unless context is done, in which case event is
+// released
+func send(ctx context.Context, e Event, out chan<- Event) bool {
+	select {
+	case out <- e:
+		return true
+	case <-ctx.Done():
+		e.Release()
+		return false
+	}
+}
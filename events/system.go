@@ -0,0 +1,29 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package events ...
+package events
+
+import (
+	"strings"
+	"time"
+)
+
+// NewSystemEvent - Will build synthetic event emitted by the connection itself
+// rather than received from the transport (e.g. ConnectedEvent). Name is used
+// both as event topic and type, payload is empty.
+func NewSystemEvent(name string, data map[string]interface{}) Event {
+	e := Event{Message: &message{topic: name}, EventType: name, Data: acquireData(), ReceivedAt: time.Now()}
+
+	for key, value := range data {
+		e.Data[key] = value
+	}
+
+	return e
+}
+
+// IsSystem - Will return whenever event is synthetic one built by NewSystemEvent
+func (e *Event) IsSystem() bool {
+	return e.Message != nil && strings.HasPrefix(e.Topic(), SystemEventPrefix) && strings.HasSuffix(e.Topic(), SystemEventPrefix)
+}
@@ -0,0 +1,77 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package events ...
+package events
+
+import "strings"
+
+// TopicParts - Will return topic split on `/`. Empty segments (leading, trailing
+// or double slashes) are ignored.
+func (e *Event) TopicParts() []string {
+	if e.Message == nil {
+		return []string{}
+	}
+
+	return splitTopic(e.Topic())
+}
+
+// TopicPart - Will return topic segment by its index or empty string in case
+// that topic does not have that many segments
+func (e *Event) TopicPart(index int) string {
+	parts := e.TopicParts()
+
+	if index < 0 || index >= len(parts) {
+		return ""
+	}
+
+	return parts[index]
+}
+
+// TopicMatch - Will match event topic against the pattern and return named
+// segments. Pattern segments are either literals, named segments (`:id`),
+// single level wildcards (`+`) or trailing multi level wildcard (`#`).
+// Example: `devices/:id/telemetry` matched against `devices/abc/telemetry`
+// returns map[id:abc]
+func (e *Event) TopicMatch(pattern string) (map[string]string, bool) {
+	parts := e.TopicParts()
+	segments := map[string]string{}
+
+	for i, segment := range splitTopic(pattern) {
+		if segment == "#" {
+			return segments, true
+		}
+
+		if i >= len(parts) {
+			return nil, false
+		}
+
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			segments[segment[1:]] = parts[i]
+		case segment == "+":
+		case segment != parts[i]:
+			return nil, false
+		}
+	}
+
+	if len(splitTopic(pattern)) != len(parts) {
+		return nil, false
+	}
+
+	return segments, true
+}
+
+// splitTopic - Will split topic on `/` dropping empty segments
+func splitTopic(topic string) []string {
+	parts := []string{}
+
+	for _, part := range strings.Split(topic, "/") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return parts
+}
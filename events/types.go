@@ -0,0 +1,81 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package events ...
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/powerunit-io/platform/utils"
+)
+
+// TypeRegistry - Go types events are decoded into, registered by topic filter.
+// Zero value is ready to use.
+type TypeRegistry struct {
+	filters []string
+	types   []reflect.Type
+	lock    sync.RWMutex
+}
+
+// Register - Will register prototype type for topic filter (mqtt wildcards are
+// supported). Prototype may be value or pointer, decoded value is always a
+// pointer to fresh instance. Filters are matched in order they were registered.
+func (r *TypeRegistry) Register(filter string, prototype interface{}) {
+	t := reflect.TypeOf(prototype)
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.filters = append(r.filters, filter)
+	r.types = append(r.types, t)
+}
+
+// Decode - Will unmarshal event payload into fresh instance of the type
+// registered for event topic, see Event.Decoded. Events on topics without
+// registered type are left untouched.
+func (r *TypeRegistry) Decode(e *Event) error {
+	t, ok := r.lookup(e.Topic())
+
+	if !ok {
+		return nil
+	}
+
+	value := reflect.New(t).Interface()
+
+	if err := json.Unmarshal(e.Payload(), value); err != nil {
+		return fmt.Errorf("Could not decode event on (topic: %s) into (type: %s) due to (err: %s)", e.Topic(), t, err)
+	}
+
+	e.decoded = value
+
+	return nil
+}
+
+// lookup - Will return type registered for the first filter matching the topic
+func (r *TypeRegistry) lookup(topic string) (reflect.Type, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for i, filter := range r.filters {
+		if _, ok := utils.MatchTopic(filter, topic); ok {
+			return r.types[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// Decoded - Will return value payload was decoded into (pointer to the type
+// registered for event topic) or nil in case that there is no such type
+func (e *Event) Decoded() interface{} {
+	return e.decoded
+}
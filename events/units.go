@@ -0,0 +1,153 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package events ...
+package events
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/powerunit-io/platform/utils"
+)
+
+// UnitRule - Conversion of single numeric event data field into canonical unit
+type UnitRule struct {
+	// Topic - Topic filter (wildcards supported) rule applies to. Empty means
+	// that rule applies to all topics.
+	Topic string `json:"topic"`
+
+	// Field - Dot separated path of the field within event data (e.g.
+	// `sensors.temperature`)
+	Field string `json:"field"`
+
+	// From - Unit field is reported in by devices
+	From string `json:"from"`
+
+	// To - Canonical unit field is converted into
+	To string `json:"to"`
+}
+
+// unit - Unit expressed relative to the base unit of its dimension as
+// base = value * factor + offset
+type unit struct {
+	dimension string
+	factor    float64
+	offset    float64
+}
+
+// units - Supported units, lower cased
+var units = map[string]unit{
+	"c":          {"temperature", 1, 0},
+	"celsius":    {"temperature", 1, 0},
+	"f":          {"temperature", 5.0 / 9.0, -32 * 5.0 / 9.0},
+	"fahrenheit": {"temperature", 5.0 / 9.0, -32 * 5.0 / 9.0},
+	"k":          {"temperature", 1, -273.15},
+	"kelvin":     {"temperature", 1, -273.15},
+
+	"pa":  {"pressure", 1, 0},
+	"hpa": {"pressure", 100, 0},
+	"kpa": {"pressure", 1000, 0},
+	"bar": {"pressure", 100000, 0},
+	"psi": {"pressure", 6894.757293168, 0},
+
+	"m":  {"length", 1, 0},
+	"cm": {"length", 0.01, 0},
+	"mm": {"length", 0.001, 0},
+	"km": {"length", 1000, 0},
+	"in": {"length", 0.0254, 0},
+	"ft": {"length", 0.3048, 0},
+
+	"m/s":  {"speed", 1, 0},
+	"km/h": {"speed", 1 / 3.6, 0},
+	"mph":  {"speed", 0.44704, 0},
+
+	"kg": {"mass", 1, 0},
+	"g":  {"mass", 0.001, 0},
+	"lb": {"mass", 0.45359237, 0},
+
+	"wh":  {"energy", 1, 0},
+	"kwh": {"energy", 1000, 0},
+}
+
+// ConvertUnit - Will convert value between units of the same dimension
+func ConvertUnit(value float64, from, to string) (float64, error) {
+	source, ok := units[strings.ToLower(from)]
+
+	if !ok {
+		return 0, fmt.Errorf("Could not convert (unit: %s) as it's not supported", from)
+	}
+
+	target, ok := units[strings.ToLower(to)]
+
+	if !ok {
+		return 0, fmt.Errorf("Could not convert (unit: %s) as it's not supported", to)
+	}
+
+	if source.dimension != target.dimension {
+		return 0, fmt.Errorf(
+			"Could not convert (unit: %s) of (dimension: %s) into (unit: %s) of (dimension: %s)",
+			from, source.dimension, to, target.dimension,
+		)
+	}
+
+	base := value*source.factor + source.offset
+	return (base - target.offset) / target.factor, nil
+}
+
+// NormalizeUnits - Will return transform rewriting numeric event data fields
+// into canonical units according to rules. Fields which are missing or not
+// numeric are left untouched. Error is returned in case that any of the rules
+// refers to unsupported units or converts between different dimensions.
+func NormalizeUnits(rules []UnitRule) (Transform, error) {
+	for _, rule := range rules {
+		if rule.Field == "" {
+			return nil, fmt.Errorf("Could not use unit (rule: %v) as field is not set", rule)
+		}
+
+		if _, err := ConvertUnit(0, rule.From, rule.To); err != nil {
+			return nil, fmt.Errorf("Could not use unit (rule: %v) due to (err: %s)", rule, err)
+		}
+	}
+
+	return func(e Event) (Event, error) {
+		for _, rule := range rules {
+			if rule.Topic != "" && e.Message != nil {
+				if _, ok := utils.MatchTopic(rule.Topic, e.Topic()); !ok {
+					continue
+				}
+			}
+
+			parent, key := lookupField(e.Data, rule.Field)
+
+			if parent == nil {
+				continue
+			}
+
+			if value, ok := utils.AsFloat(parent[key]); ok {
+				parent[key], _ = ConvertUnit(value, rule.From, rule.To)
+			}
+		}
+
+		return e, nil
+	}, nil
+}
+
+// lookupField - Will return map holding the dot separated field together with
+// the last path segment. Nil map is returned in case that path does not exist.
+func lookupField(data map[string]interface{}, field string) (map[string]interface{}, string) {
+	segments := strings.Split(field, ".")
+
+	for _, segment := range segments[:len(segments)-1] {
+		nested, ok := utils.AsStringMap(data[segment])
+
+		if !ok {
+			return nil, ""
+		}
+
+		data = nested
+	}
+
+	return data, segments[len(segments)-1]
+}
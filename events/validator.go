@@ -0,0 +1,43 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package events ...
+package events
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Validator - Will return error describing why raw payload is malformed
+type Validator func(topic string, payload []byte) error
+
+var (
+	validators     = map[string]Validator{}
+	validatorsLock sync.RWMutex
+)
+
+// RegisterValidator - Register payload validator (schema) under name so it can
+// be referenced by connection configuration
+func RegisterValidator(name string, validator Validator) {
+	validatorsLock.Lock()
+	defer validatorsLock.Unlock()
+
+	validators[name] = validator
+}
+
+// GetValidator - Return validator registered under name or error in case that
+// one is not registered
+func GetValidator(name string) (Validator, error) {
+	validatorsLock.RLock()
+	defer validatorsLock.RUnlock()
+
+	validator, ok := validators[name]
+
+	if !ok {
+		return nil, fmt.Errorf("Could not find payload validator registered as (schema: %s)", name)
+	}
+
+	return validator, nil
+}
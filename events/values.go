@@ -0,0 +1,18 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package events ...
+package events
+
+// ConnValues - Connection-wide constant context (tenant, environment, ...)
+// attached by the connection to every event it emits, as opposed to the per
+// message data. Values are shared by all events of the connection and MUST be
+// treated as read only.
+type ConnValues map[string]interface{}
+
+// ConnValue - Will return connection context value stored under the key. Nil is
+// returned for unknown keys and for events not carrying connection context.
+func (e *Event) ConnValue(key string) interface{} {
+	return e.ConnValues[key]
+}
@@ -5,8 +5,46 @@
 // Package events ...
 package events
 
+import "errors"
+
+const (
+	// JSONCodecName - Name of JSONCodec
+	JSONCodecName = "json"
+
+	// BinaryCodecName - Name of BinaryCodec
+	BinaryCodecName = "binary"
+
+	// TextPayloadEncoding - JSONCodec record payload is json string holding
+	// payload which is not valid json
+	TextPayloadEncoding = "text"
+
+	// Base64PayloadEncoding - JSONCodec record payload is json string holding
+	// base64 encoded payload which is not valid utf-8
+	Base64PayloadEncoding = "base64"
+
+	// BinaryHeaderSize - Size of fixed binary record fields (timestamp, topic
+	// length and payload length)
+	BinaryHeaderSize = 14
+
+	// SystemEventPrefix - Topics of system events start and end with it. Devices
+	// MUST NOT publish to such topics.
+	SystemEventPrefix = "__"
+
+	// ConnectedEvent - System event emitted once connection is (re)established
+	ConnectedEvent = "__connected__"
+)
+
 var (
+	// ErrNilMessage - Returned by NewEvent when there is no message to build
+	// event out of
+	ErrNilMessage = errors.New("event message is nil")
 
 	// AvailableEventTypes - m = meassurement | t = trigger
 	AvailableEventTypes = []string{"m", "t"}
+
+	// Codecs - Available event codecs by name
+	Codecs = map[string]Codec{
+		JSONCodecName:   JSONCodec{},
+		BinaryCodecName: BinaryCodec{},
+	}
 )
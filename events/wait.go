@@ -0,0 +1,41 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package events ...
+package events
+
+import (
+	"fmt"
+	"time"
+)
+
+// WaitFor - Will read single event from the channel or return error once timeout
+// expires. Intended for tests.
+func WaitFor(events <-chan Event, timeout time.Duration) (Event, error) {
+	return WaitForMatching(events, func(Event) bool { return true }, timeout)
+}
+
+// WaitForMatching - Will read events from the channel until one satisfies the
+// predicate or timeout expires. Events not matching are consumed and released.
+// Closed channel is reported as error. Intended for tests.
+func WaitForMatching(events <-chan Event, pred func(Event) bool, timeout time.Duration) (Event, error) {
+	deadline := time.After(timeout)
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return Event{}, fmt.Errorf("Could not receive matching event as events channel is closed")
+			}
+
+			if pred(event) {
+				return event, nil
+			}
+
+			event.Release()
+		case <-deadline:
+			return Event{}, fmt.Errorf("Could not receive matching event within (timeout: %s)", timeout)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package platform
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/powerunit-io/platform/events"
 	. "github.com/smartystreets/goconvey/convey"
@@ -59,4 +61,228 @@ func TestNewEventCreation(t *testing.T) {
 		So(e, ShouldHaveSameTypeAs, events.Event{})
 	})
 
+	Convey("Retained Flag Is Propagated", t, func() {
+		e, _ := events.NewEvent(&msg)
+		So(e.Retained, ShouldBeTrue)
+	})
+
+}
+
+// TestNewEventNilMessage - Ensure that event is never built without message
+func TestNewEventNilMessage(t *testing.T) {
+	Convey("Nil Message Is Rejected", t, func() {
+		e, err := events.NewEvent(nil)
+		So(err, ShouldEqual, events.ErrNilMessage)
+		So(e.Message, ShouldBeNil)
+	})
+}
+
+// TestEventTopicParsing - Ensure that topic helpers ignore empty segments and
+// that named segments are extracted by TopicMatch
+func TestEventTopicParsing(t *testing.T) {
+	msg := TestMessage{false, byte(0), false, "/devices/abc//telemetry/", 01, []byte{}}
+	e := events.Event{Message: &msg}
+
+	Convey("Topic Parts Ignore Empty Segments", t, func() {
+		So(e.TopicParts(), ShouldResemble, []string{"devices", "abc", "telemetry"})
+		So(e.TopicPart(1), ShouldEqual, "abc")
+		So(e.TopicPart(5), ShouldEqual, "")
+	})
+
+	Convey("Named Segments Are Extracted", t, func() {
+		segments, ok := e.TopicMatch("devices/:id/telemetry")
+		So(ok, ShouldBeTrue)
+		So(segments["id"], ShouldEqual, "abc")
+
+		_, ok = e.TopicMatch("devices/:id/#")
+		So(ok, ShouldBeTrue)
+	})
+
+	Convey("Mismatching Pattern Is Rejected", t, func() {
+		_, ok := e.TopicMatch("devices/:id/status")
+		So(ok, ShouldBeFalse)
+
+		_, ok = e.TopicMatch("devices/:id")
+		So(ok, ShouldBeFalse)
+	})
+}
+
+// TestEventTypeDecoding - Ensure that events are decoded into type registered
+// for matching topic filter
+func TestEventTypeDecoding(t *testing.T) {
+	type Switch struct {
+		DeviceID string `json:"device_id"`
+	}
+
+	registry := events.TypeRegistry{}
+	registry.Register("devices/+/switch", Switch{})
+
+	Convey("Matching Topic Is Decoded", t, func() {
+		msg := TestMessage{false, byte(0), false, "devices/bedroom/switch", 01, []byte(`{"device_id": "bedroom-switch"}`)}
+		e := events.Event{Message: &msg}
+
+		So(registry.Decode(&e), ShouldBeNil)
+		So(e.Decoded(), ShouldResemble, &Switch{DeviceID: "bedroom-switch"})
+	})
+
+	Convey("Other Topics Are Left Untouched", t, func() {
+		msg := TestMessage{false, byte(0), false, "devices/bedroom/relay", 01, []byte(`{}`)}
+		e := events.Event{Message: &msg}
+
+		So(registry.Decode(&e), ShouldBeNil)
+		So(e.Decoded(), ShouldBeNil)
+	})
+}
+
+// TestEventClone - Ensure that clone data and payload are independent of the
+// original event
+func TestEventClone(t *testing.T) {
+	msg := TestMessage{false, byte(1), false, "devices/abc", 01, []byte(`{"hello": "world"}`)}
+	e := events.Event{Message: &msg, EventType: "status", Data: map[string]interface{}{
+		"nested": map[string]interface{}{"value": 1},
+	}}
+
+	clone := e.Clone()
+	clone.Data["nested"].(map[string]interface{})["value"] = 2
+	clone.Payload()[0] = '['
+
+	Convey("Original Event Is Untouched", t, func() {
+		So(e.Data["nested"].(map[string]interface{})["value"], ShouldEqual, 1)
+		So(string(e.Payload()), ShouldEqual, `{"hello": "world"}`)
+	})
+
+	Convey("Clone Keeps Message Details", t, func() {
+		So(clone.EventType, ShouldEqual, "status")
+		So(clone.Topic(), ShouldEqual, "devices/abc")
+		So(clone.Qos(), ShouldEqual, byte(1))
+	})
+}
+
+// TestEventUnitNormalization - Ensure that event data fields are converted into
+// canonical units for matching topics only
+func TestEventUnitNormalization(t *testing.T) {
+	transform, err := events.NormalizeUnits([]events.UnitRule{
+		{Topic: "devices/+/climate", Field: "temperature", From: "F", To: "C"},
+		{Field: "tank.pressure", From: "psi", To: "bar"},
+	})
+
+	Convey("Rules Are Accepted", t, func() {
+		So(err, ShouldBeNil)
+	})
+
+	msg := TestMessage{false, byte(0), false, "devices/abc/climate", 01, []byte{}}
+	e := events.Event{Message: &msg, Data: map[string]interface{}{
+		"temperature": 212.0,
+		"tank":        map[string]interface{}{"pressure": 14.503773773},
+		"humidity":    "n/a",
+	}}
+
+	Convey("Fields Are Converted", t, func() {
+		e, err = transform(e)
+		So(err, ShouldBeNil)
+		So(e.Data["temperature"], ShouldAlmostEqual, 100.0, 0.0001)
+		So(e.Data["tank"].(map[string]interface{})["pressure"], ShouldAlmostEqual, 1.0, 0.0001)
+		So(e.Data["humidity"], ShouldEqual, "n/a")
+	})
+
+	Convey("Mismatching Dimensions Are Rejected", t, func() {
+		_, err := events.NormalizeUnits([]events.UnitRule{{Field: "temperature", From: "F", To: "bar"}})
+		So(err, ShouldNotBeNil)
+	})
+}
+
+// TestEventCodecs - Ensure that every codec restores message, receive time and
+// event fields of encoded event
+func TestEventCodecs(t *testing.T) {
+	msg := TestMessage{false, byte(0), false, "devices/switch", 01, []byte(`{"device_id":"bedroom-switch","type":"t","data":{"on":true}}`)}
+	e, _ := events.NewEvent(&msg)
+	e.ReceivedAt = time.Unix(1500000000, 0)
+
+	for name, codec := range events.Codecs {
+		data, err := codec.Encode(e)
+
+		Convey("Event Is Encoded With "+name, t, func() {
+			So(err, ShouldBeNil)
+		})
+
+		decoded, err := codec.Decode(data)
+
+		Convey("Event Is Decoded With "+name, t, func() {
+			So(err, ShouldBeNil)
+			So(decoded.Topic(), ShouldEqual, "devices/switch")
+			So(string(decoded.Payload()), ShouldEqual, string(msg.payload))
+			So(decoded.ReceivedAt.Equal(e.ReceivedAt), ShouldBeTrue)
+			So(decoded.DeviceID, ShouldEqual, "bedroom-switch")
+			So(decoded.Data["on"], ShouldEqual, true)
+		})
+	}
+}
+
+// TestEventStream - Ensure that stream stages are applied in order and that
+// batches are flushed once source is closed
+func TestEventStream(t *testing.T) {
+	source := make(chan events.Event, 5)
+
+	for i := 0; i < 5; i++ {
+		msg := TestMessage{false, byte(0), false, "devices/abc", uint16(i), []byte{}}
+		source <- events.Event{Message: &msg, EventType: "m", Data: map[string]interface{}{"value": i}}
+	}
+	close(source)
+
+	batches := [][]events.Event{}
+
+	err := events.NewStream(source).
+		Filter(func(e events.Event) bool { return e.Data["value"].(int)%2 == 0 }).
+		Map(func(e events.Event) events.Event { e.EventType = "t"; return e }).
+		Batch(2, time.Second).
+		ForEach(context.Background(), func(batch []events.Event) { batches = append(batches, batch) })
+
+	Convey("Events Are Filtered, Mapped And Batched", t, func() {
+		So(err, ShouldBeNil)
+		So(len(batches), ShouldEqual, 2)
+		So(len(batches[0]), ShouldEqual, 2)
+		So(len(batches[1]), ShouldEqual, 1)
+		So(batches[1][0].Data["value"], ShouldEqual, 4)
+		So(batches[0][0].EventType, ShouldEqual, "t")
+	})
+}
+
+// TestEventWaitForClosed - Ensure that waiting on closed channel fails right
+// away instead of returning zero events
+func TestEventWaitForClosed(t *testing.T) {
+	source := make(chan events.Event)
+	close(source)
+
+	_, err := events.WaitForMatching(source, func(events.Event) bool { return true }, time.Second)
+
+	Convey("Closed Channel Is Reported", t, func() {
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "closed")
+	})
+}
+
+// TestEventCodecPayloads - Ensure that json string and binary payloads survive
+// codec round trip unchanged
+func TestEventCodecPayloads(t *testing.T) {
+	payloads := map[string][]byte{
+		"Json String": []byte(`"ON"`),
+		"Plain Text":  []byte("ON"),
+		"Binary":      {0xff, 0xfe, 0x00, 0x01},
+	}
+
+	for name, payload := range payloads {
+		msg := TestMessage{false, byte(0), false, "devices/switch", 01, payload}
+
+		for codecName, codec := range events.Codecs {
+			data, err := codec.Encode(events.Event{Message: &msg})
+
+			Convey(name+" Payload Is Kept By "+codecName, t, func() {
+				So(err, ShouldBeNil)
+
+				decoded, err := codec.Decode(data)
+				So(err, ShouldBeNil)
+				So(decoded.Payload(), ShouldResemble, payload)
+			})
+		}
+	}
 }
@@ -1,5 +1,7 @@
 package logging
 
+import "time"
+
 const (
 	// FormatterForceColors -
 	FormatterForceColors = true
@@ -9,4 +11,10 @@ const (
 
 	// DefaultLoggingLevel -
 	DefaultLoggingLevel = "DEBUG"
+
+	// BumpedLoggingLevel - Level used when bumping logging level at runtime
+	BumpedLoggingLevel = "debug"
+
+	// BumpedLoggingDuration - How long bumped logging level stays active
+	BumpedLoggingDuration = 10 * time.Minute
 )
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/powerunit-io/platform/utils"
@@ -12,8 +14,21 @@ import (
 // Logger -
 type Logger struct {
 	logrus.Logger
+
+	fields logrus.Fields
 }
 
+// Logging level is global so is the bump state shared by all loggers. Each bump
+// gets new generation so revert of superseded bump is a no-op even when its
+// timer already fired.
+var (
+	bumpBase       logrus.Level
+	bumpActive     bool
+	bumpGeneration uint64
+	bumpRevert     *time.Timer
+	bumpLock       sync.Mutex
+)
+
 // SetFormatter -
 func (l *Logger) SetFormatter(formatter logrus.Formatter) {
 	logrus.SetFormatter(formatter)
@@ -44,39 +59,134 @@ func (l *Logger) SetLevel(levelEnv string) error {
 	return nil
 }
 
+// Level - Will return name of currently active logging level
+func (l *Logger) Level() string {
+	return logrus.GetLevel().String()
+}
+
+// ChangeLevel - Will change logging level at runtime (e.g. "debug", "info").
+// Active bump (see BumpLevel) is cancelled, so the change is never reverted.
+func (l *Logger) ChangeLevel(level string) error {
+	lvl, err := logrus.ParseLevel(level)
+
+	if err != nil {
+		return fmt.Errorf("Could not change logging level due to (err: %s)", err)
+	}
+
+	bumpLock.Lock()
+	defer bumpLock.Unlock()
+
+	if bumpRevert != nil {
+		bumpRevert.Stop()
+	}
+
+	// Revert which already fired and waits for the lock sees stale generation
+	bumpGeneration++
+	bumpActive, bumpRevert = false, nil
+
+	logrus.SetLevel(lvl)
+
+	return nil
+}
+
+// BumpLevel - Will change logging level for the duration and than revert it back
+// to level which was active before the bump. Bumping again while bump is active
+// extends it, but still reverts to the original level.
+func (l *Logger) BumpLevel(level string, duration time.Duration) error {
+	lvl, err := logrus.ParseLevel(level)
+
+	if err != nil {
+		return fmt.Errorf("Could not bump logging level due to (err: %s)", err)
+	}
+
+	bumpLock.Lock()
+	defer bumpLock.Unlock()
+
+	if !bumpActive {
+		bumpBase, bumpActive = logrus.GetLevel(), true
+	}
+
+	if bumpRevert != nil {
+		bumpRevert.Stop()
+	}
+
+	bumpGeneration++
+	generation := bumpGeneration
+
+	logrus.SetLevel(lvl)
+	logrus.Warningf("Logging (level: %s) bumped to (level: %s) for (duration: %s)", bumpBase, lvl, duration)
+
+	bumpRevert = time.AfterFunc(duration, func() {
+		bumpLock.Lock()
+		defer bumpLock.Unlock()
+
+		if generation != bumpGeneration {
+			return
+		}
+
+		logrus.SetLevel(bumpBase)
+		logrus.Warningf("Logging (level: %s) reverted back after (duration: %s)", bumpBase, duration)
+
+		bumpActive, bumpRevert = false, nil
+	})
+
+	return nil
+}
+
 // Error -
 func (l *Logger) Error(format string, args ...interface{}) {
-	logrus.Errorf(format, args...)
+	l.entry().Errorf(format, args...)
 }
 
 // Warning -
 func (l *Logger) Warning(format string, args ...interface{}) {
-	logrus.Warningf(format, args...)
+	l.entry().Warningf(format, args...)
 }
 
 // Info -
 func (l *Logger) Info(format string, args ...interface{}) {
-	logrus.Infof(format, args...)
+	l.entry().Infof(format, args...)
 }
 
 // Fatal -
 func (l *Logger) Fatal(format string, args ...interface{}) {
-	logrus.Fatalf(format, args...)
+	l.entry().Fatalf(format, args...)
 }
 
 // Debug -
 func (l *Logger) Debug(format string, args ...interface{}) {
-	logrus.Debugf(format, args...)
+	l.entry().Debugf(format, args...)
 }
 
 // Print -
 func (l *Logger) Print(args ...interface{}) {
-	logrus.Print(args...)
+	l.entry().Print(args...)
 }
 
 // Panic -
 func (l *Logger) Panic(format string, args ...interface{}) {
-	logrus.Panicf(format, args...)
+	l.entry().Panicf(format, args...)
+}
+
+// WithTags - Will return logger attaching tags as structured fields to every
+// logged entry. Fields of the logger are kept.
+func (l *Logger) WithTags(tags map[string]string) *Logger {
+	fields := logrus.Fields{}
+
+	for key, value := range l.fields {
+		fields[key] = value
+	}
+
+	for key, value := range tags {
+		fields[key] = value
+	}
+
+	return &Logger{fields: fields}
+}
+
+// entry - Will return log entry carrying logger fields
+func (l *Logger) entry() *logrus.Entry {
+	return logrus.WithFields(l.fields)
 }
 
 // GetContextLogger -
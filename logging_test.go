@@ -2,6 +2,7 @@ package platform
 
 import (
 	"testing"
+	"time"
 
 	"github.com/powerunit-io/platform/logging"
 	. "github.com/smartystreets/goconvey/convey"
@@ -13,7 +14,7 @@ import (
 // logging context. In addition to that we'll check few additional methods such
 // as context
 func TestLoggingManager(t *testing.T) {
-	logger := logging.New()
+	logger := logging.New(map[string]interface{}{})
 
 	Convey("Logging Manager Pointer Check", t, func() {
 		So(*logger, ShouldHaveSameTypeAs, logging.Logger{})
@@ -27,4 +28,34 @@ func TestLoggingManager(t *testing.T) {
 		So(context, ShouldHaveSameTypeAs, &logrus.Entry{})
 	})
 
+	Convey("Bumped Level Is Reverted", t, func() {
+		So(logger.ChangeLevel("info"), ShouldBeNil)
+		So(logger.BumpLevel("debug", 50*time.Millisecond), ShouldBeNil)
+		So(logger.Level(), ShouldEqual, "debug")
+
+		time.Sleep(100 * time.Millisecond)
+		So(logger.Level(), ShouldEqual, "info")
+	})
+
+	Convey("Repeated Bump Reverts To Original Level", t, func() {
+		So(logger.ChangeLevel("info"), ShouldBeNil)
+		So(logger.BumpLevel("debug", 20*time.Millisecond), ShouldBeNil)
+		So(logger.BumpLevel("debug", 40*time.Millisecond), ShouldBeNil)
+
+		time.Sleep(30 * time.Millisecond)
+		So(logger.Level(), ShouldEqual, "debug")
+
+		time.Sleep(50 * time.Millisecond)
+		So(logger.Level(), ShouldEqual, "info")
+	})
+
+	Convey("Level Changed During Bump Is Kept", t, func() {
+		So(logger.ChangeLevel("info"), ShouldBeNil)
+		So(logger.BumpLevel("debug", 20*time.Millisecond), ShouldBeNil)
+		So(logger.ChangeLevel("warning"), ShouldBeNil)
+
+		time.Sleep(50 * time.Millisecond)
+		So(logger.Level(), ShouldEqual, "warning")
+	})
+
 }
@@ -0,0 +1,40 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package managers ...
+package managers
+
+// Dependent - Optional interface of services which are meaningful only once
+// services (usually connections) they depend on are ready
+type Dependent interface {
+	// DependsOn - Will return names of services within bound manager
+	DependsOn() []string
+
+	// BindDependencies - Will bind manager dependencies are looked up in
+	BindDependencies(m Manager)
+}
+
+// WaitingOn - Will return names of dependencies which are missing within the
+// manager or are not ready yet
+func WaitingOn(m Manager, dependencies []string) []string {
+	waiting := []string{}
+
+	if len(dependencies) == 0 {
+		return waiting
+	}
+
+	if m == nil {
+		return append(waiting, dependencies...)
+	}
+
+	detail := m.ReadyDetail()
+
+	for _, dependency := range dependencies {
+		if !detail[dependency] {
+			waiting = append(waiting, dependency)
+		}
+	}
+
+	return waiting
+}
@@ -0,0 +1,119 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package managers ...
+package managers
+
+import "github.com/powerunit-io/platform/events"
+
+// EventSource - Optional interface of services emitting events (connections)
+type EventSource interface {
+	DrainEvents() chan events.Event
+}
+
+// EventPiper - Optional interface of event sources able to push their events
+// into caller channel exclusively (see mqtt Connection.PipeTo), so no other
+// consumer can split the stream afterwards
+type EventPiper interface {
+	PipeTo(ch chan<- events.Event) error
+}
+
+// AllEvents - Will return single channel merging events of all attached
+// EventSource services, each event tagged with name of its service (see
+// events.Event.Source). Services attached later (Attach, CommitReload) are
+// merged automatically and removed or replaced ones are left out. Services
+// satisfying EventPiper are claimed exclusively, so their DrainEvents returns
+// nil afterwards. Channel is shared by all callers and never closed.
+func (m *BaseManager) AllEvents() <-chan events.Event {
+	m.mergeLock.Lock()
+	defer m.mergeLock.Unlock()
+
+	if m.merged != nil {
+		return m.merged
+	}
+
+	m.merged = make(chan events.Event, AllEventsBuffer)
+	m.fanIns = make(map[string]chan bool)
+
+	for name, service := range m.Services {
+		m.fanIn(name, service)
+	}
+
+	return m.merged
+}
+
+// merge - Will include attached service in the merged stream in case that
+// AllEvents is in use
+func (m *BaseManager) merge(name string, service Service) {
+	m.mergeLock.Lock()
+	defer m.mergeLock.Unlock()
+
+	if m.merged != nil {
+		m.fanIn(name, service)
+	}
+}
+
+// unmerge - Will stop forwarding events of the service into merged stream
+func (m *BaseManager) unmerge(name string) {
+	m.mergeLock.Lock()
+	defer m.mergeLock.Unlock()
+
+	if stop, ok := m.fanIns[name]; ok {
+		close(stop)
+		delete(m.fanIns, name)
+	}
+}
+
+// fanIn - Will start forwarding events of the service into merged stream. MUST
+// be called with mergeLock held.
+func (m *BaseManager) fanIn(name string, service Service) {
+	source, ok := service.(EventSource)
+
+	if !ok {
+		return
+	}
+
+	var received <-chan events.Event
+
+	if piper, ok := service.(EventPiper); ok {
+		piped := make(chan events.Event)
+
+		if err := piper.PipeTo(piped); err != nil {
+			m.Warning("Could not merge events of (service: %s) due to (error: %s)", name, err)
+			return
+		}
+
+		received = piped
+	} else if drained := source.DrainEvents(); drained != nil {
+		received = drained
+	} else {
+		m.Warning("Could not merge events of (service: %s) as its events are already consumed", name)
+		return
+	}
+
+	stop := make(chan bool)
+	m.fanIns[name] = stop
+
+	go func() {
+		for {
+			select {
+			case e, ok := <-received:
+				if !ok {
+					return
+				}
+
+				e.Source = name
+
+				select {
+				case m.merged <- e:
+				case <-stop:
+					e.Release()
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
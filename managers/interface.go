@@ -5,6 +5,12 @@
 // Package managers ...
 package managers
 
+import (
+	"time"
+
+	"github.com/powerunit-io/platform/events"
+)
+
 // Service -
 type Service interface {
 	Start(done chan bool) error
@@ -19,12 +25,58 @@ type Service interface {
 	Adapter() interface{}
 }
 
+// Waiter - Optional interface of services running background goroutines. Wait
+// MUST block until all of them exited.
+type Waiter interface {
+	Wait()
+}
+
+// Inspectable - Optional interface services can satisfy in order to expose more
+// details about themselves through Manager.ListServices
+type Inspectable interface {
+	Kind() string
+	Status() string
+
+	// Redacted - Will return service configuration with credentials masked
+	Redacted() map[string]interface{}
+}
+
+// Tagged - Optional interface of services labelled by `tags` configuration.
+// Tags can be used to filter Manager.ListServices.
+type Tagged interface {
+	Tags() map[string]string
+}
+
+// ServiceInfo - Snapshot of the attached service used by debug/admin listings
+type ServiceInfo struct {
+	Name   string                 `json:"name"`
+	Kind   string                 `json:"kind"`
+	Status string                 `json:"status"`
+	Tags   map[string]string      `json:"tags,omitempty"`
+	Config map[string]interface{} `json:"config"`
+}
+
 // Manager -
 type Manager interface {
 	Attach(m string, bm Service) error
 	Remove(m string) error
 	All() map[string]Service
 	List() []string
+	ListServices(withTags ...string) []ServiceInfo
 	Get(m string) (Service, error)
 	Exists(m string) bool
+	AllEvents() <-chan events.Event
+
+	Ready() bool
+	ReadyDetail() map[string]bool
+
+	ValidateAll() error
+
+	StopAll() error
+	WaitStopped(timeout time.Duration) error
+	OnShutdown(fn func() error)
+
+	PrepareReload(services map[string]Service, done chan bool) error
+	CommitReload() error
+	RollbackReload() error
 }
@@ -7,7 +7,12 @@ package managers
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/powerunit-io/platform/events"
 	"github.com/powerunit-io/platform/logging"
 )
 
@@ -16,6 +21,13 @@ type BaseManager struct {
 	*logging.Logger
 
 	Services map[string]Service
+
+	hooks  []func() error
+	staged map[string]Service
+
+	merged    chan events.Event
+	fanIns    map[string]chan bool
+	mergeLock sync.Mutex
 }
 
 // Attach - Assing service to manager instance. Return error if service is
@@ -29,6 +41,7 @@ func (m *BaseManager) Attach(s string, i Service) error {
 	}
 
 	m.Services[s] = i
+	m.merge(s, i)
 
 	return nil
 }
@@ -43,6 +56,8 @@ func (m *BaseManager) Remove(s string) error {
 	}
 
 	delete(m.Services, s)
+	m.unmerge(s)
+
 	return nil
 }
 
@@ -62,6 +77,50 @@ func (m *BaseManager) List() []string {
 	return services
 }
 
+// ListServices - Return info about all available/attached services within manager
+// instance. Services not satisfying Inspectable will be reported with unknown
+// kind and status. In case that tags (`key=value` or just `key`) are provided,
+// only Tagged services matching all of them are returned.
+func (m *BaseManager) ListServices(withTags ...string) []ServiceInfo {
+	services := []ServiceInfo{}
+
+	for name, service := range m.Services {
+		info := ServiceInfo{Name: name, Kind: KindUnknown, Status: StatusUnknown}
+
+		if tagged, ok := service.(Tagged); ok {
+			info.Tags = tagged.Tags()
+		}
+
+		if !matchTags(info.Tags, withTags) {
+			continue
+		}
+
+		if inspectable, ok := service.(Inspectable); ok {
+			info.Kind = inspectable.Kind()
+			info.Status = inspectable.Status()
+			info.Config = inspectable.Redacted()
+		}
+
+		services = append(services, info)
+	}
+
+	return services
+}
+
+// matchTags - Will check whenever tags satisfy all `key=value` or `key` filters
+func matchTags(tags map[string]string, filters []string) bool {
+	for _, filter := range filters {
+		parts := strings.SplitN(filter, "=", 2)
+		value, ok := tags[parts[0]]
+
+		if !ok || (len(parts) == 2 && value != parts[1]) {
+			return false
+		}
+	}
+
+	return true
+}
+
 // Get - Return attached service or return error if it does not exist.
 func (m *BaseManager) Get(s string) (Service, error) {
 	if !m.Exists(s) {
@@ -84,3 +143,130 @@ func (m *BaseManager) Exists(s string) bool {
 
 	return false
 }
+
+// OnShutdown - Register hook which will be executed by StopAll once all services
+// are stopped. Hooks are executed in order they were registered.
+func (m *BaseManager) OnShutdown(fn func() error) {
+	m.hooks = append(m.hooks, fn)
+}
+
+// StopAll - Will stop all services concurrently, wait for them and than execute
+// shutdown hooks. Errors from both services and hooks are logged and returned
+// aggregated.
+func (m *BaseManager) StopAll() error {
+	errs := m.stop(m.Services, StopReason{Kind: StopShutdown})
+
+	for i, hook := range m.hooks {
+		if err := hook(); err != nil {
+			m.Error("Shutdown (hook: %d) failed due to (error: %s)", i, err)
+			errs = append(errs, fmt.Errorf("(hook: %d) - (error: %s)", i, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("Could not gracefully stop all services (errors: %v)", errs)
+	}
+
+	return nil
+}
+
+// WaitStopped - Will block until background goroutines of all services (those
+// satisfying Waiter) exited or timeout expires. Error lists services which did
+// not stop in time.
+func (m *BaseManager) WaitStopped(timeout time.Duration) error {
+	var lock sync.Mutex
+
+	pending := map[string]bool{}
+	stopped := make(chan bool, len(m.Services))
+
+	for name, service := range m.Services {
+		waiter, ok := service.(Waiter)
+
+		if !ok {
+			continue
+		}
+
+		pending[name] = true
+
+		go func(n string, w Waiter) {
+			w.Wait()
+
+			lock.Lock()
+			delete(pending, n)
+			lock.Unlock()
+
+			stopped <- true
+		}(name, waiter)
+	}
+
+	deadline := time.After(timeout)
+
+	for {
+		lock.Lock()
+		remaining := len(pending)
+		lock.Unlock()
+
+		if remaining == 0 {
+			return nil
+		}
+
+		select {
+		case <-stopped:
+		case <-deadline:
+			lock.Lock()
+			defer lock.Unlock()
+
+			names := []string{}
+			for name := range pending {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			return fmt.Errorf("Could not wait for (services: %v) to stop within (timeout: %s)", names, timeout)
+		}
+	}
+}
+
+// stopAll - Will stop services replaced by reload returning aggregated error
+func (m *BaseManager) stopAll(services map[string]Service) error {
+	if errs := m.stop(services, StopReason{Kind: StopReload}); len(errs) > 0 {
+		return fmt.Errorf("Could not gracefully stop all services (errors: %v)", errs)
+	}
+
+	return nil
+}
+
+// stop - Will stop services concurrently and wait for them. Services keeping
+// track of stop reason (see ReasonStopper) are told why they are stopped.
+func (m *BaseManager) stop(services map[string]Service, reason StopReason) []error {
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+
+	errs := []error{}
+
+	for name, service := range services {
+		wg.Add(1)
+
+		go func(n string, s Service) {
+			defer wg.Done()
+
+			stop := s.Stop
+
+			if stopper, ok := s.(ReasonStopper); ok {
+				stop = func() error { return stopper.StopWithReason(reason) }
+			}
+
+			if err := stop(); err != nil {
+				m.Error("Could not stop (service: %s) due to (error: %s)", n, err)
+
+				lock.Lock()
+				errs = append(errs, fmt.Errorf("(service: %s) - (error: %s)", n, err))
+				lock.Unlock()
+			}
+		}(name, service)
+	}
+
+	wg.Wait()
+
+	return errs
+}
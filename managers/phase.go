@@ -0,0 +1,90 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package managers ...
+package managers
+
+import "sync"
+
+// Phase - Lifecycle phase of the connection
+type Phase int
+
+const (
+	// PhaseStopped - Not started yet or stopped
+	PhaseStopped Phase = iota
+
+	// PhaseConnecting - Started but never connected yet
+	PhaseConnecting
+
+	// PhaseConnected - Connected
+	PhaseConnected
+
+	// PhaseReconnecting - Was connected, lost connection and is reconnecting
+	PhaseReconnecting
+
+	// PhaseFailed - Gave up (re)connecting, see Reconnect of the connection
+	PhaseFailed
+)
+
+// String -
+func (p Phase) String() string {
+	switch p {
+	case PhaseConnecting:
+		return "connecting"
+	case PhaseConnected:
+		return "connected"
+	case PhaseReconnecting:
+		return "reconnecting"
+	case PhaseFailed:
+		return "failed"
+	}
+
+	return "stopped"
+}
+
+// MarshalText - Phases are serialized by their names
+func (p Phase) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// PhaseTracker - Concurrency safe phase holder to be embedded by services
+type PhaseTracker struct {
+	phase     Phase
+	connected bool
+	lock      sync.Mutex
+}
+
+// Phase - Will return current phase
+func (t *PhaseTracker) Phase() Phase {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.phase
+}
+
+// SetPhase - Will change current phase
+func (t *PhaseTracker) SetPhase(phase Phase) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if phase == PhaseConnected {
+		t.connected = true
+	}
+
+	t.phase = phase
+}
+
+// Disconnected - Will move into connecting or reconnecting phase, depending on
+// whenever service was ever connected
+func (t *PhaseTracker) Disconnected() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.connected {
+		t.phase = PhaseReconnecting
+		return
+	}
+
+	t.phase = PhaseConnecting
+}
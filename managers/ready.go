@@ -0,0 +1,53 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package managers ...
+package managers
+
+// Phased - Optional interface of services tracking their lifecycle phase
+type Phased interface {
+	Phase() Phase
+}
+
+// Ready - Will return true only when every attached service is ready. See
+// ReadyDetail for what ready means.
+func (m *BaseManager) Ready() bool {
+	for _, ready := range m.ReadyDetail() {
+		if !ready {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ReadyDetail - Will return readiness of each attached service. Phased services
+// are ready only once connected (still connecting or reconnecting is not ready),
+// Inspectable ones when they report connected or running status. Services
+// reporting neither are considered ready.
+func (m *BaseManager) ReadyDetail() map[string]bool {
+	detail := map[string]bool{}
+
+	for name, service := range m.Services {
+		detail[name] = ready(service)
+	}
+
+	return detail
+}
+
+// ready - Will return readiness of single service
+func ready(service Service) bool {
+	if phased, ok := service.(Phased); ok {
+		if phased.Phase() != PhaseConnected {
+			return false
+		}
+	}
+
+	if inspectable, ok := service.(Inspectable); ok {
+		status := inspectable.Status()
+		return status == StatusConnected || status == StatusRunning
+	}
+
+	return true
+}
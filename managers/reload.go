@@ -0,0 +1,148 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package managers ...
+package managers
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Drainer - Optional interface of services buffering events. Drain MUST block
+// until buffered events are consumed or timeout expires.
+type Drainer interface {
+	Drain(timeout time.Duration) error
+}
+
+// PrepareReload - First phase of the reload. Will validate and start new services
+// alongside currently attached ones. In case that any of them fails, already
+// started new services are stopped and current services stay untouched. Only
+// exception are current services sharing client id on the same broker with
+// new ones (see ClientIdentified), as broker would drop one of the sessions.
+// They are drained and stopped before new services start, so they are gone
+// even if the reload is rolled back afterwards.
+func (m *BaseManager) PrepareReload(services map[string]Service, done chan bool) error {
+	if m.staged != nil {
+		return fmt.Errorf("Could not prepare reload as another reload is already prepared")
+	}
+
+	for name, service := range services {
+		if err := service.Validate(); err != nil {
+			return fmt.Errorf("Could not prepare reload as (service: %s) is not valid (error: %s)", name, err)
+		}
+	}
+
+	if errs := duplicateClients(services); len(errs) > 0 {
+		return fmt.Errorf("Could not prepare reload as services share client ids (errors: %v)", errs)
+	}
+
+	if taken := m.takenClients(services); len(taken) > 0 {
+		m.Warning("Stopping (services: %v) ahead of reload as new services reuse their client ids", sortedNames(taken))
+		m.drainAll(taken)
+
+		for name := range taken {
+			delete(m.Services, name)
+			m.unmerge(name)
+		}
+
+		if err := m.stopAll(taken); err != nil {
+			m.Error("Could not stop services ahead of reload due to (error: %s)", err)
+		}
+	}
+
+	started := map[string]Service{}
+
+	for name, service := range services {
+		if err := service.Start(done); err != nil {
+			m.Error("Could not start reloaded (service: %s) due to (error: %s). Rolling back ...", name, err)
+			m.stopAll(started)
+			return fmt.Errorf("Could not prepare reload as (service: %s) failed to start (error: %s)", name, err)
+		}
+
+		started[name] = service
+	}
+
+	m.staged = started
+	return nil
+}
+
+// CommitReload - Second phase of the reload. Will drain (see Drainer) and stop
+// current services and replace them with services started by PrepareReload.
+func (m *BaseManager) CommitReload() error {
+	if m.staged == nil {
+		return fmt.Errorf("Could not commit reload as no reload is prepared")
+	}
+
+	m.drainAll(m.Services)
+
+	old := m.Services
+
+	m.Services = m.staged
+	m.staged = nil
+
+	for name := range old {
+		m.unmerge(name)
+	}
+
+	for name, service := range m.Services {
+		m.merge(name, service)
+	}
+
+	return m.stopAll(old)
+}
+
+// RollbackReload - Will stop services started by PrepareReload keeping current
+// services attached
+func (m *BaseManager) RollbackReload() error {
+	if m.staged == nil {
+		return fmt.Errorf("Could not rollback reload as no reload is prepared")
+	}
+
+	staged := m.staged
+	m.staged = nil
+
+	return m.stopAll(staged)
+}
+
+// drainAll - Will drain services buffering events (see Drainer)
+func (m *BaseManager) drainAll(services map[string]Service) {
+	for name, service := range services {
+		if drainer, ok := service.(Drainer); ok {
+			if err := drainer.Drain(ReloadDrainTimeout); err != nil {
+				m.Warning("Could not fully drain (service: %s) due to (error: %s)", name, err)
+			}
+		}
+	}
+}
+
+// takenClients - Will return attached services sharing client id on the same
+// broker with any of the services
+func (m *BaseManager) takenClients(services map[string]Service) map[string]Service {
+	taken := map[string]Service{}
+
+	for name, current := range m.Services {
+		for _, service := range services {
+			if sharesClient(current, service) {
+				taken[name] = current
+				break
+			}
+		}
+	}
+
+	return taken
+}
+
+// sortedNames - Will return sorted names of the services
+func sortedNames(services map[string]Service) []string {
+	names := make([]string, 0, len(services))
+
+	for name := range services {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,37 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package managers ...
+package managers
+
+import "fmt"
+
+// StopReason - Why service stopped, so workers can tell planned shutdown (flush
+// buffers) from reload or fatal error (discard them). Zero value means that
+// service was not stopped.
+type StopReason struct {
+	Kind string `json:"kind"`
+	Err  error  `json:"-"`
+}
+
+// String -
+func (r StopReason) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("%s (err: %s)", r.Kind, r.Err)
+	}
+
+	return r.Kind
+}
+
+// Stopped - Will return whenever reason is set
+func (r StopReason) Stopped() bool {
+	return r.Kind != ""
+}
+
+// ReasonStopper - Optional interface of services which keep track of why they
+// were stopped. Manager stops them through StopWithReason instead of Stop.
+type ReasonStopper interface {
+	StopWithReason(reason StopReason) error
+	StopReason() StopReason
+}
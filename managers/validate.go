@@ -0,0 +1,166 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package managers ...
+package managers
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PartialValidator - Optional interface of services whose configuration is
+// split into shared (connection) part and service specific (topic) part
+type PartialValidator interface {
+	ValidateConnection() error
+	ValidateTopic() error
+
+	// ConnectionFingerprint - Services with equal, non empty, fingerprint share
+	// connection configuration
+	ConnectionFingerprint() string
+}
+
+// ClientIdentified - Optional interface of services connecting to the broker
+// under client id. Broker drops older session once another one connects with
+// the same client id, so services sharing client id on the same broker keep
+// kicking each other off.
+type ClientIdentified interface {
+	// ClientIdentity - Will return client id (empty when broker assigns one)
+	// together with addresses of brokers it's used against
+	ClientIdentity() (string, []string)
+}
+
+// ValidateAll - Will validate all attached services. Shared connection part of
+// PartialValidator services is validated only once per fingerprint. Services
+// sharing client id on the same broker (see ClientIdentified) are reported as
+// well. Errors are returned aggregated.
+func (m *BaseManager) ValidateAll() error {
+	validated := map[string]error{}
+	errs := []string{}
+
+	for name, service := range m.Services {
+		if err := validate(service, validated); err != nil {
+			errs = append(errs, fmt.Sprintf("(service: %s) - (error: %s)", name, err))
+		}
+	}
+
+	for _, err := range duplicateClients(m.Services) {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("Could not validate all services (errors: %v)", errs)
+	}
+
+	return nil
+}
+
+// validate - Will validate single service reusing already validated connections
+func validate(service Service, validated map[string]error) error {
+	partial, ok := service.(PartialValidator)
+
+	if !ok {
+		return service.Validate()
+	}
+
+	fingerprint := partial.ConnectionFingerprint()
+	err, cached := validated[fingerprint]
+
+	if !cached || fingerprint == "" {
+		err = partial.ValidateConnection()
+		validated[fingerprint] = err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return partial.ValidateTopic()
+}
+
+// sharesClient - Will check whether both services use the same client id
+// against any of the same brokers
+func sharesClient(a Service, b Service) bool {
+	first, ok := a.(ClientIdentified)
+
+	if !ok {
+		return false
+	}
+
+	second, ok := b.(ClientIdentified)
+
+	if !ok {
+		return false
+	}
+
+	clientID, brokers := first.ClientIdentity()
+	otherID, others := second.ClientIdentity()
+
+	if clientID == "" || clientID != otherID {
+		return false
+	}
+
+	for _, broker := range brokers {
+		for _, other := range others {
+			if broker == other {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// duplicateClients - Will return error for each client id used by more than one
+// of the services against the same broker
+func duplicateClients(services map[string]Service) []error {
+	owners := map[string]map[string][]string{}
+
+	for name, service := range services {
+		identified, ok := service.(ClientIdentified)
+
+		if !ok {
+			continue
+		}
+
+		clientID, brokers := identified.ClientIdentity()
+
+		if clientID == "" {
+			continue
+		}
+
+		if owners[clientID] == nil {
+			owners[clientID] = map[string][]string{}
+		}
+
+		for _, broker := range brokers {
+			names := owners[clientID][broker]
+
+			// Same broker listed twice by single service is not a conflict
+			if len(names) > 0 && names[len(names)-1] == name {
+				continue
+			}
+
+			owners[clientID][broker] = append(names, name)
+		}
+	}
+
+	errs := []error{}
+
+	for clientID, brokers := range owners {
+		for broker, names := range brokers {
+			if len(names) < 2 {
+				continue
+			}
+
+			sort.Strings(names)
+			errs = append(errs, fmt.Errorf("(client_id: %s) on (broker: %s) is shared by (services: %v)", clientID, broker, names))
+		}
+	}
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+
+	return errs
+}
@@ -0,0 +1,48 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package managers ...
+package managers
+
+import "time"
+
+var (
+	// ReloadDrainTimeout - How long CommitReload waits for services to drain
+	ReloadDrainTimeout = 10 * time.Second
+
+	// AllEventsBuffer - Size of the channel returned by AllEvents
+	AllEventsBuffer = 100
+)
+
+const (
+	// KindUnknown - Service does not report its kind
+	KindUnknown = "unknown"
+
+	// StatusUnknown - Service does not report its status
+	StatusUnknown = "unknown"
+
+	// StatusConnected - Service is up and connected
+	StatusConnected = "connected"
+
+	// StatusDisconnected - Service is started but currently not connected
+	StatusDisconnected = "disconnected"
+
+	// StatusRunning - Service without connection of its own is up
+	StatusRunning = "running"
+
+	// StatusWaiting - Service is up but waits on its dependencies to be ready
+	StatusWaiting = "waiting"
+
+	// StatusStopped - Service is not started or it's stopped
+	StatusStopped = "stopped"
+
+	// StopShutdown - Service was stopped as part of graceful shutdown
+	StopShutdown = "shutdown"
+
+	// StopReload - Service was replaced by configuration reload
+	StopReload = "reload"
+
+	// StopFatal - Service gave up due to unrecoverable error
+	StopFatal = "fatal"
+)
@@ -0,0 +1,375 @@
+package platform
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/powerunit-io/platform/connections"
+	"github.com/powerunit-io/platform/events"
+	"github.com/powerunit-io/platform/logging"
+	"github.com/powerunit-io/platform/managers"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type TestService struct {
+	name     string
+	startErr error
+	started  bool
+	stopped  bool
+}
+
+func (s *TestService) Start(done chan bool) error {
+	if s.startErr != nil {
+		return s.startErr
+	}
+
+	s.started = true
+	return nil
+}
+
+func (s *TestService) Stop() error {
+	s.stopped = true
+	return nil
+}
+
+func (s *TestService) Validate() error {
+	return nil
+}
+
+func (s *TestService) Name() string {
+	return s.name
+}
+
+func (s *TestService) Adapter() interface{} {
+	return s
+}
+
+// ReasonService - Test service keeping track of stop reason
+type ReasonService struct {
+	TestService
+	reason managers.StopReason
+}
+
+func (s *ReasonService) StopWithReason(reason managers.StopReason) error {
+	s.reason = reason
+	return s.Stop()
+}
+
+func (s *ReasonService) StopReason() managers.StopReason {
+	return s.reason
+}
+
+// EventService - Test service emitting events
+type EventService struct {
+	TestService
+	events chan events.Event
+}
+
+func (s *EventService) DrainEvents() chan events.Event {
+	return s.events
+}
+
+// newTestManager - Will return manager with single, started, service attached
+func newTestManager() (*managers.BaseManager, *TestService) {
+	old := &TestService{name: "old", started: true}
+
+	return &managers.BaseManager{
+		Logger:   logging.New(map[string]interface{}{}),
+		Services: map[string]managers.Service{"old": old},
+	}, old
+}
+
+// TestManagerReload - Ensure that committed reload replaces services and that
+// failing reload leaves current services untouched
+func TestManagerReload(t *testing.T) {
+
+	Convey("Committed Reload Replaces Services", t, func() {
+		manager, old := newTestManager()
+		fresh := &TestService{name: "new"}
+
+		So(manager.PrepareReload(map[string]managers.Service{"new": fresh}, nil), ShouldBeNil)
+		So(fresh.started, ShouldBeTrue)
+		So(old.stopped, ShouldBeFalse)
+
+		So(manager.CommitReload(), ShouldBeNil)
+		So(old.stopped, ShouldBeTrue)
+		So(manager.List(), ShouldResemble, []string{"new"})
+	})
+
+	Convey("Failing Reload Is Rolled Back", t, func() {
+		manager, old := newTestManager()
+		broken := &TestService{name: "broken", startErr: fmt.Errorf("unreachable")}
+
+		So(manager.PrepareReload(map[string]managers.Service{"broken": broken}, nil), ShouldNotBeNil)
+		So(old.stopped, ShouldBeFalse)
+		So(manager.List(), ShouldResemble, []string{"old"})
+		So(manager.CommitReload(), ShouldNotBeNil)
+	})
+
+	Convey("Service Reusing Client Id Is Stopped Before Reload Starts", t, func() {
+		manager, old := newTestManager()
+		current := &ClientTestService{TestService{name: "current", started: true}, "worker", []string{"broker-a:1883"}}
+		manager.Attach("current", current)
+
+		fresh := &ClientTestService{TestService{name: "fresh"}, "worker", []string{"broker-a:1883"}}
+
+		So(manager.PrepareReload(map[string]managers.Service{"fresh": fresh}, nil), ShouldBeNil)
+		So(current.stopped, ShouldBeTrue)
+		So(old.stopped, ShouldBeFalse)
+		So(manager.List(), ShouldResemble, []string{"old"})
+
+		So(manager.CommitReload(), ShouldBeNil)
+		So(manager.List(), ShouldResemble, []string{"fresh"})
+	})
+}
+
+// TestManagerStopReason - Ensure that services are told whenever they are
+// stopped by shutdown or replaced by reload
+func TestManagerStopReason(t *testing.T) {
+
+	Convey("Reloaded Service Is Stopped With Reload Reason", t, func() {
+		manager, _ := newTestManager()
+		old := &ReasonService{TestService: TestService{name: "old", started: true}}
+		manager.Services["old"] = old
+
+		So(manager.PrepareReload(map[string]managers.Service{"new": &TestService{name: "new"}}, nil), ShouldBeNil)
+		So(manager.CommitReload(), ShouldBeNil)
+		So(old.StopReason().Kind, ShouldEqual, managers.StopReload)
+	})
+
+	Convey("Service Is Stopped With Shutdown Reason", t, func() {
+		manager, _ := newTestManager()
+		service := &ReasonService{TestService: TestService{name: "service", started: true}}
+		manager.Services["service"] = service
+
+		So(manager.StopAll(), ShouldBeNil)
+		So(service.StopReason().Kind, ShouldEqual, managers.StopShutdown)
+	})
+}
+
+// TestManagerAllEvents - Ensure that events of attached services are merged
+// and tagged by service name, including services attached later
+func TestManagerAllEvents(t *testing.T) {
+
+	Convey("Events Of All Sources Are Merged", t, func() {
+		manager, _ := newTestManager()
+		first := &EventService{TestService: TestService{name: "first"}, events: make(chan events.Event, 1)}
+		second := &EventService{TestService: TestService{name: "second"}, events: make(chan events.Event, 1)}
+		manager.Services["first"] = first
+
+		merged := manager.AllEvents()
+		So(manager.Attach("second", second), ShouldBeNil)
+
+		for _, source := range []*EventService{first, second} {
+			source.events <- events.Event{EventType: "t"}
+
+			select {
+			case e := <-merged:
+				So(e.Source, ShouldEqual, source.name)
+			case <-time.After(time.Second):
+				t.Fatalf("Expected event of (service: %s) to be merged", source.name)
+			}
+		}
+
+		So(manager.Remove("second"), ShouldBeNil)
+		second.events <- events.Event{EventType: "t"}
+
+		select {
+		case e := <-merged:
+			t.Errorf("Expected removed service not to be merged but got (event: %v)", e)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
+
+// TestManagerAllEventsClaim - Ensure that merged connection can't be drained
+// by another consumer splitting its events
+func TestManagerAllEventsClaim(t *testing.T) {
+
+	Convey("Merged Connection Is Claimed Exclusively", t, func() {
+		manager, _ := newTestManager()
+		connection := testMqtt("all-events-claim", withConnection("clientId", "all-events-claim"))
+		So(manager.Attach("mqtt", connection), ShouldBeNil)
+
+		merged := manager.AllEvents()
+		So(connection.DrainEvents(), ShouldBeNil)
+
+		connection.BrokerHandler(nil, testMsg("powerunit-io-bridge", TestMsgTrigger))
+
+		select {
+		case e := <-merged:
+			So(e.Source, ShouldEqual, "mqtt")
+			So(e.DeviceID, ShouldEqual, "bedroom-switch")
+		case <-time.After(time.Second):
+			t.Fatal("Expected event of mqtt connection to be merged")
+		}
+	})
+}
+
+// TestManagerReadiness - Ensure that service still connecting is not ready
+func TestManagerReadiness(t *testing.T) {
+
+	Convey("Connecting Service Is Not Ready", t, func() {
+		manager, _ := newTestManager()
+		phased := &PhasedTestService{TestService: TestService{name: "phased"}}
+		phased.SetPhase(managers.PhaseConnecting)
+		manager.Attach("phased", phased)
+
+		So(manager.Ready(), ShouldBeFalse)
+		So(manager.ReadyDetail(), ShouldResemble, map[string]bool{"old": true, "phased": false})
+
+		phased.SetPhase(managers.PhaseConnected)
+		So(manager.Ready(), ShouldBeTrue)
+	})
+
+	Convey("Missing Dependency Is Waited On", t, func() {
+		manager, _ := newTestManager()
+
+		So(managers.WaitingOn(manager, []string{"old", "missing"}), ShouldResemble, []string{"missing"})
+		So(managers.WaitingOn(nil, []string{"old"}), ShouldResemble, []string{"old"})
+	})
+}
+
+type PhasedTestService struct {
+	TestService
+	managers.PhaseTracker
+}
+
+type BlockingTestService struct {
+	TestService
+}
+
+func (s *BlockingTestService) Wait() {
+	select {}
+}
+
+// TestManagerWaitStopped - Ensure that services which did not stop in time are
+// reported
+func TestManagerWaitStopped(t *testing.T) {
+
+	Convey("Blocking Service Is Reported", t, func() {
+		manager, _ := newTestManager()
+		manager.Attach("blocking", &BlockingTestService{TestService{name: "blocking"}})
+
+		err := manager.WaitStopped(10 * time.Millisecond)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "[blocking]")
+	})
+}
+
+type ClientTestService struct {
+	TestService
+	clientID string
+	brokers  []string
+}
+
+func (s *ClientTestService) ClientIdentity() (string, []string) {
+	return s.clientID, s.brokers
+}
+
+// TestManagerDuplicateClientIDs - Ensure that services sharing client id on the
+// same broker are reported by name
+func TestManagerDuplicateClientIDs(t *testing.T) {
+
+	Convey("Shared Client Id Is Reported", t, func() {
+		manager, _ := newTestManager()
+		manager.Attach("first", &ClientTestService{TestService{name: "first"}, "worker", []string{"broker-a:1883", "broker-b:1883"}})
+		manager.Attach("second", &ClientTestService{TestService{name: "second"}, "worker", []string{"broker-b:1883"}})
+		manager.Attach("third", &ClientTestService{TestService{name: "third"}, "worker", []string{"broker-c:1883"}})
+
+		err := manager.ValidateAll()
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "(client_id: worker) on (broker: broker-b:1883) is shared by (services: [first second])")
+		So(err.Error(), ShouldNotContainSubstring, "third")
+	})
+}
+
+type SharedTestService struct {
+	TestService
+	validations *int
+}
+
+func (s *SharedTestService) ValidateConnection() error {
+	*s.validations++
+	return nil
+}
+
+func (s *SharedTestService) ValidateTopic() error {
+	return nil
+}
+
+func (s *SharedTestService) ConnectionFingerprint() string {
+	return "shared"
+}
+
+// TestManagerValidateAll - Ensure that shared connection block is validated once
+func TestManagerValidateAll(t *testing.T) {
+
+	Convey("Shared Connection Is Validated Once", t, func() {
+		manager, _ := newTestManager()
+		validations := 0
+
+		manager.Attach("first", &SharedTestService{TestService{name: "first"}, &validations})
+		manager.Attach("second", &SharedTestService{TestService{name: "second"}, &validations})
+
+		So(manager.ValidateAll(), ShouldBeNil)
+		So(validations, ShouldEqual, 1)
+	})
+}
+
+type TaggedTestService struct {
+	TestService
+	tags map[string]string
+}
+
+func (s *TaggedTestService) Tags() map[string]string {
+	return s.tags
+}
+
+// TestManagerListServicesByTag - Ensure that services are filtered by tags
+func TestManagerListServicesByTag(t *testing.T) {
+
+	Convey("Services Are Filtered By Tags", t, func() {
+		manager, _ := newTestManager()
+		manager.Attach("eu", &TaggedTestService{TestService{name: "eu"}, map[string]string{"region": "eu", "env": "prod"}})
+		manager.Attach("us", &TaggedTestService{TestService{name: "us"}, map[string]string{"region": "us"}})
+
+		So(len(manager.ListServices()), ShouldEqual, 3)
+		So(len(manager.ListServices("region")), ShouldEqual, 2)
+
+		services := manager.ListServices("region=eu", "env")
+		So(len(services), ShouldEqual, 1)
+		So(services[0].Name, ShouldEqual, "eu")
+	})
+}
+
+// TestConnectionsHealthHandler - Ensure that health handler responds with 503
+// until every connection is healthy
+func TestConnectionsHealthHandler(t *testing.T) {
+	manager, _ := newTestManager()
+	phased := &PhasedTestService{TestService: TestService{name: "phased"}}
+	phased.SetPhase(managers.PhaseConnecting)
+	manager.Attach("phased", phased)
+
+	handler := connections.HealthHandler(manager)
+
+	Convey("Connecting Connection Is Unhealthy", t, func() {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest("GET", "/healthz", nil))
+
+		So(recorder.Code, ShouldEqual, http.StatusServiceUnavailable)
+		So(recorder.Body.String(), ShouldContainSubstring, `"healthy":false`)
+	})
+
+	Convey("Connected Connection Is Healthy", t, func() {
+		phased.SetPhase(managers.PhaseConnected)
+
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest("GET", "/healthz", nil))
+
+		So(recorder.Code, ShouldEqual, http.StatusOK)
+	})
+}
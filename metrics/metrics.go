@@ -0,0 +1,144 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package metrics ...
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// metric - Single named metric holding all of its labelled series
+type metric struct {
+	maxSeries int
+	series    map[string]*series
+}
+
+// series - Single labelled value of the metric
+type series struct {
+	labels map[string]string
+	value  float64
+}
+
+var (
+	registry = make(map[string]*metric)
+	lock     sync.Mutex
+)
+
+// SetMaxSeries - Will set cardinality guard (max distinct label sets) for the
+// metric. Once reached, new label sets are collapsed into OverflowLabelValue.
+func SetMaxSeries(name string, max int) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	get(name).maxSeries = max
+}
+
+// Inc - Will increment metric series identified by labels (may be nil)
+func Inc(name string, labels map[string]string) {
+	Add(name, 1, labels)
+}
+
+// Add - Will add value to metric series identified by labels (may be nil)
+func Add(name string, value float64, labels map[string]string) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	m := get(name)
+	key := labelsKey(labels)
+
+	if _, ok := m.series[key]; !ok && len(m.series) >= m.maxSeries {
+		labels = overflow(labels)
+		key = labelsKey(labels)
+	}
+
+	if _, ok := m.series[key]; !ok {
+		m.series[key] = &series{labels: labels}
+	}
+
+	m.series[key].value += value
+}
+
+// Value - Will return current value of metric series identified by labels
+func Value(name string, labels map[string]string) float64 {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if m, ok := registry[name]; ok {
+		if s, ok := m.series[labelsKey(labels)]; ok {
+			return s.value
+		}
+	}
+
+	return 0
+}
+
+// Write - Will write all metrics in prometheus text exposition format
+func Write(w io.Writer) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	names := []string{}
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		keys := []string{}
+		for key := range registry[name].series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			s := registry[name].series[key]
+
+			if _, err := fmt.Fprintf(w, "%s%s %v\n", name, key, s.value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// get - Will return metric by name creating it when needed. Lock MUST be held.
+func get(name string) *metric {
+	if _, ok := registry[name]; !ok {
+		registry[name] = &metric{maxSeries: DefaultMaxSeries, series: make(map[string]*series)}
+	}
+
+	return registry[name]
+}
+
+// labelsKey - Will build prometheus style, sorted, label set representation
+func labelsKey(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	pairs := []string{}
+	for key, value := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", key, value))
+	}
+	sort.Strings(pairs)
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// overflow - Will return copy of labels with all values collapsed
+func overflow(labels map[string]string) map[string]string {
+	collapsed := make(map[string]string, len(labels))
+
+	for key := range labels {
+		collapsed[key] = OverflowLabelValue
+	}
+
+	return collapsed
+}
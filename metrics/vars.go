@@ -0,0 +1,14 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package metrics ...
+package metrics
+
+var (
+	// DefaultMaxSeries - Default cardinality guard applied to every metric
+	DefaultMaxSeries = 1000
+
+	// OverflowLabelValue - Label value used once metric cardinality guard is hit
+	OverflowLabelValue = "other"
+)
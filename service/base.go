@@ -1,9 +1,12 @@
 package service
 
 import (
+	"runtime"
+
 	"github.com/powerunit-io/platform/config"
 	"github.com/powerunit-io/platform/logging"
 	"github.com/powerunit-io/platform/managers"
+	"github.com/powerunit-io/platform/utils"
 )
 
 // BaseService -
@@ -29,3 +32,16 @@ func (bs *BaseService) Version() float64 {
 func (bs *BaseService) Name() string {
 	return bs.Config.Get("service_name").(string)
 }
+
+// SetGoMaxProcs - Will set process wide GOMAXPROCS out of env variable (number
+// of CPUs by default) and return resolved value. It affects Go scheduling only,
+// per connection parallelism is tuned by connection `poolSize` and `bufferSize`
+// config.
+func (bs *BaseService) SetGoMaxProcs(env string) int {
+	procs := utils.GetProcessCount(env)
+	previous := runtime.GOMAXPROCS(procs)
+
+	bs.Info("Set GOMAXPROCS to (procs: %d) - (previous: %d) - (env: %s)", procs, previous, env)
+
+	return procs
+}
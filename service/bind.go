@@ -0,0 +1,52 @@
+package service
+
+import (
+	"sort"
+
+	"github.com/powerunit-io/platform/connections"
+	"github.com/powerunit-io/platform/managers"
+)
+
+// Bindable - Optional interface of workers which need to look up connections
+// they consume from. Bind is invoked before worker is started.
+type Bindable interface {
+	Bind(bind BindManager) error
+}
+
+// BindManager - Read only registry of named connections handed over to the
+// bound services
+type BindManager struct {
+	connections map[string]connections.Connection
+}
+
+// NewBindManager - Will build bind manager out of connections attached to the
+// manager. Services not satisfying connections.Connection are skipped.
+func NewBindManager(m managers.Manager) BindManager {
+	bind := BindManager{connections: make(map[string]connections.Connection)}
+
+	for name, service := range m.All() {
+		if connection, ok := service.(connections.Connection); ok {
+			bind.connections[name] = connection
+		}
+	}
+
+	return bind
+}
+
+// Connection - Will return connection attached under the name
+func (b BindManager) Connection(name string) (connections.Connection, bool) {
+	connection, ok := b.connections[name]
+	return connection, ok
+}
+
+// Connections - Will return sorted names of available connections
+func (b BindManager) Connections() []string {
+	names := make([]string, 0, len(b.connections))
+
+	for name := range b.connections {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
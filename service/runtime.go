@@ -7,6 +7,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/powerunit-io/platform/logging"
 	"github.com/powerunit-io/platform/managers"
 )
 
@@ -15,6 +16,7 @@ func (bs *BaseService) Start() error {
 	bs.Info("Starting up (service: %s) - (ver: %v)", bs.Name(), bs.Config.Get("service_version"))
 
 	go bs.HandleSigterm()
+	go bs.HandleSigusr1()
 
 	if err := bs.StartDevices(); err != nil {
 		return err
@@ -38,31 +40,20 @@ func (bs *BaseService) Start() error {
 	return nil
 }
 
-// Stop -
+// Stop - Will stop connections, devices and workers (in that order) including
+// their shutdown hooks and than exit
 func (bs *BaseService) Stop() error {
 
-	for _, service := range bs.Connections.All() {
-		go func(s managers.Service) {
-			if err := s.Stop(); err != nil {
-				bs.Error("Could not stop (connection: %s) due to (error: %s)", s.Name(), err)
-			}
-		}(service)
+	if err := bs.Connections.StopAll(); err != nil {
+		bs.Error("Could not stop connections due to (error: %s)", err)
 	}
 
-	for _, service := range bs.Devices.All() {
-		go func(s managers.Service) {
-			if err := s.Stop(); err != nil {
-				bs.Error("Could not stop (device: %s) due to (error: %s)", s.Name(), err)
-			}
-		}(service)
+	if err := bs.Devices.StopAll(); err != nil {
+		bs.Error("Could not stop devices due to (error: %s)", err)
 	}
 
-	for _, service := range bs.Workers.All() {
-		go func(s managers.Service) {
-			if err := s.Stop(); err != nil {
-				bs.Error("Could not stop (worker: %s) due to (error: %s)", s.Name(), err)
-			}
-		}(service)
+	if err := bs.Workers.StopAll(); err != nil {
+		bs.Error("Could not stop workers due to (error: %s)", err)
 	}
 
 	bs.Warning("Service (name: %s) is now stopped!", bs.Name())
@@ -124,7 +115,20 @@ func (bs *BaseService) StartWorkers() (err error) {
 
 	bs.Info("Available (workers: %v). Starting them up now ...", bs.Workers.List())
 
+	bind := NewBindManager(bs.Connections)
+
 	for _, service := range bs.Workers.All() {
+		if dependent, ok := service.(managers.Dependent); ok {
+			dependent.BindDependencies(bs.Connections)
+		}
+
+		if bindable, ok := service.(Bindable); ok {
+			if err := bindable.Bind(bind); err != nil {
+				bs.Error("Could not bind (worker: %s) to (connections: %v) due to (error: %s)", service.Name(), bind.Connections(), err)
+				return err
+			}
+		}
+
 		wg.Add(1)
 
 		go func(s managers.Service) {
@@ -153,3 +157,17 @@ func (bs *BaseService) HandleSigterm() {
 
 	close(bs.Done)
 }
+
+// HandleSigusr1 - Will bump logging to debug level for a while each time SIGUSR1
+// is received. Level is reverted back automatically, see logging.BumpLevel
+func (bs *BaseService) HandleSigusr1() {
+	susr := make(chan os.Signal, 1)
+
+	signal.Notify(susr, syscall.SIGUSR1)
+
+	for range susr {
+		if err := bs.BumpLevel(logging.BumpedLoggingLevel, logging.BumpedLoggingDuration); err != nil {
+			bs.Error("Could not bump logging level due to (err: %s)", err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ParseBrokerAddress - Will split broker address into host and port. Bare host
+// (`localhost`), `host:port`, bracketed IPv6 with or without port
+// (`[::1]:1883`, `[::1]`) and bare IPv6 (`::1`) are accepted. Default port is
+// used when address has none. Port, when separator is present, MUST NOT be empty
+// and MUST be within 1-65535 range.
+func ParseBrokerAddress(addr string, defaultPort int) (host string, port int, err error) {
+	addr = strings.TrimSpace(addr)
+	portPart, hasPort := "", false
+
+	switch {
+	case strings.HasPrefix(addr, "["):
+		end := strings.Index(addr, "]")
+
+		if end < 0 {
+			return "", 0, fmt.Errorf("Could not parse broker (address: %s) as ipv6 bracket is not closed", addr)
+		}
+
+		host = addr[1:end]
+		rest := addr[end+1:]
+
+		if rest != "" {
+			if !strings.HasPrefix(rest, ":") {
+				return "", 0, fmt.Errorf("Could not parse broker (address: %s) as port MUST follow ipv6 bracket", addr)
+			}
+
+			portPart, hasPort = rest[1:], true
+		}
+	case strings.Count(addr, ":") > 1:
+		if net.ParseIP(addr) == nil {
+			return "", 0, fmt.Errorf("Could not parse broker (address: %s) as it's neither host:port nor ipv6 address", addr)
+		}
+
+		host = addr
+	case strings.Contains(addr, ":"):
+		if host, portPart, err = net.SplitHostPort(addr); err != nil {
+			return "", 0, fmt.Errorf("Could not parse broker (address: %s) due to (err: %s)", addr, err)
+		}
+
+		hasPort = true
+	default:
+		host = addr
+	}
+
+	if host == "" {
+		return "", 0, fmt.Errorf("Could not parse broker (address: %s) as host is empty", addr)
+	}
+
+	port = defaultPort
+
+	if hasPort {
+		if portPart == "" {
+			return "", 0, fmt.Errorf("Could not parse broker (address: %s) as port is empty", addr)
+		}
+
+		if port, err = strconv.Atoi(portPart); err != nil {
+			return "", 0, fmt.Errorf("Could not parse broker (address: %s) as (port: %s) is not a number", addr, portPart)
+		}
+	}
+
+	if port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("Could not parse broker (address: %s) as (port: %d) is out of range", addr, port)
+	}
+
+	return host, port, nil
+}
@@ -17,7 +17,7 @@ func GetProcessCount(env string) int {
 
 	pc, err := strconv.Atoi(os.Getenv(envName))
 
-	if err != nil {
+	if err != nil || pc < 1 {
 		pc = runtime.NumCPU()
 	}
 
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryOptions - Retry behaviour. Zero MaxAttempts means retry until context is
+// done. Zero Multiplier defaults to 2 (exponential backoff).
+type RetryOptions struct {
+	MaxAttempts  int
+	BaseInterval time.Duration
+	MaxInterval  time.Duration
+	Multiplier   float64
+
+	// Jitter - Fraction interval is randomly shifted by (0.5 = ±50%)
+	Jitter float64
+}
+
+// permanentError - Error which must not be retried
+type permanentError struct {
+	err error
+}
+
+// Error -
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Permanent - Will wrap error so Retry stops immediately and returns it
+// (unwrapped)
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Retry - Will call fn until it succeeds, returns Permanent error, attempts are
+// exhausted or context is done. Last error returned by fn is returned (context
+// error in case that fn was never called).
+func Retry(ctx context.Context, opts RetryOptions, fn func() error) error {
+	var err error
+
+	interval := opts.BaseInterval
+	multiplier := opts.Multiplier
+
+	if multiplier == 0 {
+		multiplier = 2
+	}
+
+	for attempt := 1; ; attempt++ {
+		if ctx.Err() != nil {
+			if err == nil {
+				err = ctx.Err()
+			}
+
+			return err
+		}
+
+		if err = fn(); err == nil {
+			return nil
+		}
+
+		if permanent, ok := err.(*permanentError); ok {
+			return permanent.err
+		}
+
+		if opts.MaxAttempts > 0 && attempt >= opts.MaxAttempts {
+			return err
+		}
+
+		select {
+		case <-time.After(jitter(interval, opts.Jitter)):
+		case <-ctx.Done():
+			return err
+		}
+
+		interval = time.Duration(float64(interval) * multiplier)
+
+		if opts.MaxInterval > 0 && interval > opts.MaxInterval {
+			interval = opts.MaxInterval
+		}
+	}
+}
+
+// jitter - Will randomly shift interval by fraction of it
+func jitter(interval time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 {
+		return interval
+	}
+
+	return interval + time.Duration((rand.Float64()*2-1)*fraction*float64(interval))
+}
@@ -0,0 +1,37 @@
+package utils
+
+import "strings"
+
+// MatchTopic - Will check if topic matches mqtt topic filter (supporting `+` and
+// `#` wildcards) and return values captured by wildcards in order they appear
+// within the filter. Multi level wildcard captures rest of the topic.
+func MatchTopic(filter string, topic string) ([]string, bool) {
+	filterParts := strings.Split(filter, "/")
+	topicParts := strings.Split(topic, "/")
+	captures := []string{}
+
+	for i, part := range filterParts {
+		if part == "#" {
+			return append(captures, strings.Join(topicParts[i:], "/")), true
+		}
+
+		if i >= len(topicParts) {
+			return nil, false
+		}
+
+		if part == "+" {
+			captures = append(captures, topicParts[i])
+			continue
+		}
+
+		if part != topicParts[i] {
+			return nil, false
+		}
+	}
+
+	if len(filterParts) != len(topicParts) {
+		return nil, false
+	}
+
+	return captures, true
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"strconv"
+	"time"
+)
+
+// AsStringMap - Will do checked assertion of (config) value into map
+func AsStringMap(v interface{}) (map[string]interface{}, bool) {
+	value, ok := v.(map[string]interface{})
+	return value, ok
+}
+
+// AsString - Will do checked assertion of (config) value into string
+func AsString(v interface{}) (string, bool) {
+	value, ok := v.(string)
+	return value, ok
+}
+
+// AsInt - Will convert numeric config value into int. Values decoded from json
+// are float64 while values set from code are usually int, both are accepted.
+func AsInt(v interface{}) (int, bool) {
+	switch value := v.(type) {
+	case int:
+		return value, true
+	case int64:
+		return int(value), true
+	case float64:
+		return int(value), true
+	}
+
+	return 0, false
+}
+
+// AsFloat - Will convert numeric (config or event data) value into float64
+func AsFloat(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case float64:
+		return value, true
+	case float32:
+		return float64(value), true
+	case int:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	}
+
+	return 0, false
+}
+
+// AsDuration - Will convert (config) value into duration. Go duration strings
+// ("10s", "2m") are accepted, bare numbers (or numeric strings) are treated as
+// seconds for compatibility.
+func AsDuration(v interface{}) (time.Duration, bool) {
+	if seconds, ok := AsInt(v); ok {
+		if f, ok := v.(float64); ok {
+			return time.Duration(f * float64(time.Second)), true
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	value, ok := v.(string)
+
+	if !ok {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+
+	duration, err := time.ParseDuration(value)
+
+	if err != nil {
+		return 0, false
+	}
+
+	return duration, true
+}
+
+// CopyMap - Will deep copy (config) map, including nested maps and lists, so
+// the copy can be modified without affecting the original
+func CopyMap(data map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(data))
+
+	for key, value := range data {
+		copied[key] = copyValue(value)
+	}
+
+	return copied
+}
+
+// copyValue -
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return CopyMap(v)
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+
+		for i, item := range v {
+			copied[i] = copyValue(item)
+		}
+
+		return copied
+	}
+
+	return value
+}
@@ -0,0 +1,154 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/powerunit-io/platform/utils"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// TestMatchTopic - Ensure that mqtt topic filters are matched properly and that
+// wildcard values are captured in order they appear within the filter
+func TestMatchTopic(t *testing.T) {
+
+	Convey("Single Level Wildcard Captures Segment", t, func() {
+		captures, ok := utils.MatchTopic("devices/+/telemetry", "devices/abc/telemetry")
+		So(ok, ShouldBeTrue)
+		So(captures, ShouldResemble, []string{"abc"})
+	})
+
+	Convey("Multi Level Wildcard Captures Rest Of Topic", t, func() {
+		captures, ok := utils.MatchTopic("devices/+/#", "devices/abc/telemetry/celsius")
+		So(ok, ShouldBeTrue)
+		So(captures, ShouldResemble, []string{"abc", "telemetry/celsius"})
+	})
+
+	Convey("Mismatching Topics Are Rejected", t, func() {
+		_, ok := utils.MatchTopic("devices/+/telemetry", "devices/abc/status")
+		So(ok, ShouldBeFalse)
+
+		_, ok = utils.MatchTopic("devices/+", "devices/abc/telemetry")
+		So(ok, ShouldBeFalse)
+
+		_, ok = utils.MatchTopic("devices/+/telemetry", "devices/abc")
+		So(ok, ShouldBeFalse)
+	})
+}
+
+// TestCheckedAssertions - Ensure that checked assertions never panic on type
+// mismatch
+func TestCheckedAssertions(t *testing.T) {
+
+	Convey("Matching Types Are Returned", t, func() {
+		value, ok := utils.AsString("tcp")
+		So(ok, ShouldBeTrue)
+		So(value, ShouldEqual, "tcp")
+
+		data, ok := utils.AsStringMap(map[string]interface{}{"topic": "a"})
+		So(ok, ShouldBeTrue)
+		So(data["topic"], ShouldEqual, "a")
+
+		number, ok := utils.AsInt(float64(5))
+		So(ok, ShouldBeTrue)
+		So(number, ShouldEqual, 5)
+	})
+
+	Convey("Mismatching Types Are Rejected", t, func() {
+		_, ok := utils.AsString(5)
+		So(ok, ShouldBeFalse)
+
+		_, ok = utils.AsStringMap(nil)
+		So(ok, ShouldBeFalse)
+
+		_, ok = utils.AsInt("5")
+		So(ok, ShouldBeFalse)
+	})
+}
+
+// TestDurationParsing - Ensure that both duration strings and bare seconds are
+// accepted
+func TestDurationParsing(t *testing.T) {
+
+	Convey("Duration Strings Are Parsed", t, func() {
+		duration, ok := utils.AsDuration("2m")
+		So(ok, ShouldBeTrue)
+		So(duration, ShouldEqual, 2*time.Minute)
+	})
+
+	Convey("Bare Numbers Are Seconds", t, func() {
+		duration, _ := utils.AsDuration(10)
+		So(duration, ShouldEqual, 10*time.Second)
+
+		duration, _ = utils.AsDuration(float64(1.5))
+		So(duration, ShouldEqual, 1500*time.Millisecond)
+
+		duration, _ = utils.AsDuration("3")
+		So(duration, ShouldEqual, 3*time.Second)
+	})
+
+	Convey("Invalid Values Are Rejected", t, func() {
+		_, ok := utils.AsDuration("soon")
+		So(ok, ShouldBeFalse)
+	})
+}
+
+// TestRetry - Ensure that attempts are bounded and permanent errors stop retry
+func TestRetry(t *testing.T) {
+	opts := utils.RetryOptions{MaxAttempts: 3, BaseInterval: time.Millisecond}
+
+	Convey("Attempts Are Exhausted", t, func() {
+		attempts := 0
+		err := utils.Retry(context.Background(), opts, func() error {
+			attempts++
+			return fmt.Errorf("attempt %d", attempts)
+		})
+
+		So(attempts, ShouldEqual, 3)
+		So(err.Error(), ShouldEqual, "attempt 3")
+	})
+
+	Convey("Permanent Error Is Not Retried", t, func() {
+		attempts := 0
+		rejected := fmt.Errorf("rejected")
+		err := utils.Retry(context.Background(), opts, func() error {
+			attempts++
+			return utils.Permanent(rejected)
+		})
+
+		So(attempts, ShouldEqual, 1)
+		So(err, ShouldEqual, rejected)
+	})
+}
+
+// TestBrokerAddressParsing - Ensure that bare hosts and ipv6 addresses are parsed
+// and that invalid ports are rejected
+func TestBrokerAddressParsing(t *testing.T) {
+
+	Convey("Addresses Are Parsed", t, func() {
+		for addr, expected := range map[string]struct {
+			host string
+			port int
+		}{
+			"localhost":      {"localhost", 1883},
+			"localhost:8883": {"localhost", 8883},
+			"[::1]:1884":     {"::1", 1884},
+			"[::1]":          {"::1", 1883},
+			"fe80::1":        {"fe80::1", 1883},
+		} {
+			host, port, err := utils.ParseBrokerAddress(addr, 1883)
+			So(err, ShouldBeNil)
+			So(host, ShouldEqual, expected.host)
+			So(port, ShouldEqual, expected.port)
+		}
+	})
+
+	Convey("Invalid Addresses Are Rejected", t, func() {
+		for _, addr := range []string{"", ":1883", "localhost:0", "localhost:99999", "localhost:mqtt", "localhost:", "[::1]:", "[::1", "[::1]1883", "a:b:c"} {
+			_, _, err := utils.ParseBrokerAddress(addr, 1883)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
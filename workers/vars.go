@@ -0,0 +1,11 @@
+// Copyright 2015 The PowerUnit Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package workers ...
+package workers
+
+const (
+	// Kind - Kind of the service reported to the managers
+	Kind = "worker"
+)
@@ -8,12 +8,16 @@ package workers
 import (
 	"github.com/powerunit-io/platform/config"
 	"github.com/powerunit-io/platform/logging"
+	"github.com/powerunit-io/platform/managers"
+	"github.com/powerunit-io/platform/utils"
 )
 
 // WorkerBase -
 type WorkerBase struct {
 	*logging.Logger
 	*config.Config
+
+	dependencies managers.Manager
 }
 
 // Adapter - here just to satisfy interface. We really do not need this...
@@ -23,5 +27,41 @@ func (wb *WorkerBase) Adapter() interface{} {
 
 // Name - Will return name of the worker ...
 func (wb *WorkerBase) Name() string {
-	return wb.Config.Get("name").(string)
+	name, _ := utils.AsString(wb.Config.Get("name"))
+	return name
+}
+
+// Kind -
+func (wb *WorkerBase) Kind() string {
+	return Kind
+}
+
+// Status - Will return waiting status until all connections listed by
+// `depends_on` config are ready, running afterwards
+func (wb *WorkerBase) Status() string {
+	if len(managers.WaitingOn(wb.dependencies, wb.DependsOn())) > 0 {
+		return managers.StatusWaiting
+	}
+
+	return managers.StatusRunning
+}
+
+// DependsOn - Will return names of connections defined by `depends_on` config
+func (wb *WorkerBase) DependsOn() []string {
+	dependencies := []string{}
+
+	list, _ := wb.Config.Get("depends_on").([]interface{})
+
+	for _, item := range list {
+		if name, ok := utils.AsString(item); ok {
+			dependencies = append(dependencies, name)
+		}
+	}
+
+	return dependencies
+}
+
+// BindDependencies - Will bind connections manager dependencies are looked up in
+func (wb *WorkerBase) BindDependencies(m managers.Manager) {
+	wb.dependencies = m
 }